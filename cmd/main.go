@@ -1,23 +1,75 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
 
+	"github.com/aviadhaham/cloudcate/internal/config"
 	"github.com/aviadhaham/cloudcate/internal/search"
-	"github.com/aviadhaham/cloudcate/internal/web"
 )
 
+const usage = `Usage: cloudcate <command> [arguments]
+
+Commands:
+  serve                               start the web server (the default without a command)
+  search <type> <term> [flags]        search resources, <type> is a resource type or "all"
+  profiles [flags]                    list the discovered profiles
+  regions [flags]                     list the enabled regions of every profile
+
+Run "cloudcate <command> -h" for the flags of a command.
+`
+
 func main() {
+	if cacheTTLs := os.Getenv("CACHE_TTLS"); cacheTTLs != "" {
+		if err := config.ParseCacheTTLs(cacheTTLs); err != nil {
+			log.Fatalf("Failed to parse CACHE_TTLS: %v", err)
+		}
+	}
+
+	command, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
+	}
+
+	switch command {
+	case "serve":
+		runServe(args)
+	case "search":
+		os.Exit(runSearch(args))
+	case "profiles":
+		os.Exit(runProfiles(args))
+	case "regions":
+		os.Exit(runRegions(args))
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(exitUsage)
+	}
+}
+
+// loadProfiles discovers the profiles of the shared files and, with ORG_PROFILE set, the member accounts of the organization.
+func loadProfiles() []search.Profile {
 	profiles, err := search.GetProfiles()
 	if err != nil {
 		log.Fatalf("Failed to get profiles: %v", err)
 	}
 
-	if os.Getenv("PORT") == "" {
-		log.Fatalf("PORT env var is not set")
+	// with ORG_PROFILE set, every member account of the organization is searched through an assumed role
+	if orgProfile := os.Getenv("ORG_PROFILE"); orgProfile != "" {
+		orgProfiles, err := search.GetOrganizationProfiles(context.Background(), profiles, search.OrganizationOptions{
+			Profile:    orgProfile,
+			RoleName:   os.Getenv("ORG_ROLE_NAME"),
+			ExternalId: os.Getenv("ORG_EXTERNAL_ID"),
+		})
+		if err != nil {
+			log.Fatalf("Failed to discover organization accounts: %v", err)
+		}
+		log.Printf("Discovered %d organization accounts through profile '%s'", len(orgProfiles), orgProfile)
+		profiles = append(profiles, orgProfiles...)
 	}
 
-	s := web.NewServer(os.Getenv("PORT"), profiles)
-	s.Run()
+	return profiles
 }
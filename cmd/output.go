@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	formatTable  = "table"
+	formatJson   = "json"
+	formatNdjson = "ndjson"
+	formatCsv    = "csv"
+)
+
+func validFormat(format string) bool {
+	switch format {
+	case formatTable, formatJson, formatNdjson, formatCsv:
+		return true
+	}
+	return false
+}
+
+// writeRows writes rows (structs, as their JSON fields) in the given format. JSON writes the whole value
+// instead, so the command can wrap the rows with what else it reports.
+func writeRows(w io.Writer, format string, rows []interface{}, whole interface{}) error {
+	switch format {
+	case formatJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(whole)
+	case formatNdjson:
+		encoder := json.NewEncoder(w)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	columns, records, err := tabulate(rows)
+	if err != nil {
+		return err
+	}
+
+	if format == formatCsv {
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, record := range records {
+		fmt.Fprintln(writer, strings.Join(record, "\t"))
+	}
+	return writer.Flush()
+}
+
+// tabulate flattens rows into records with the union of their fields as columns, in the order they first appear.
+// The attributes of resources are flattened into columns of their own and their raw payload is left out.
+func tabulate(rows []interface{}) ([]string, [][]string, error) {
+	columns := []string{}
+	seen := map[string]bool{}
+	fieldsOfRows := []map[string]interface{}{}
+
+	for _, row := range rows {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys, err := orderedKeys(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, key := range keys {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, nil, err
+		}
+		if attributes, ok := fields[attributesField].(map[string]interface{}); ok {
+			for key, value := range attributes {
+				fields[key] = value
+			}
+		}
+		fieldsOfRows = append(fieldsOfRows, fields)
+	}
+
+	records := [][]string{}
+	for _, fields := range fieldsOfRows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = formatValue(fields[column])
+		}
+		records = append(records, record)
+	}
+	return columns, records, nil
+}
+
+// Fields of search.Resource that tabulate handles apart
+const (
+	attributesField = "attributes"
+	rawField        = "raw"
+)
+
+// orderedKeys returns the keys of a JSON object in their order, with the keys of its attributes in place
+// of the attributes.
+func orderedKeys(data []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case key == rawField:
+		case key == attributesField && len(value) > 0 && value[0] == '{':
+			attributeKeys, err := orderedKeys(value)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, attributeKeys...)
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			values = append(values, formatValue(v))
+		}
+		return strings.Join(values, ", ")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, key+"="+formatValue(value[key]))
+		}
+		return strings.Join(pairs, ", ")
+	default:
+		return fmt.Sprint(value)
+	}
+}
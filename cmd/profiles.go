@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aviadhaham/cloudcate/internal/search"
+)
+
+func runProfiles(args []string) int {
+	flags := flag.NewFlagSet("profiles", flag.ContinueOnError)
+	profiles := flags.String("profiles", "", "comma separated profile names or globs to list, e.g. prod-* (default every profile)")
+	format := flags.String("format", formatTable, "output format: table, json, ndjson or csv")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOk
+		}
+		return exitUsage
+	}
+	if !validFormat(*format) {
+		fmt.Fprintf(os.Stderr, "invalid format %q: expected table, json, ndjson or csv\n", *format)
+		return exitUsage
+	}
+
+	listed := search.FilterProfiles(loadProfiles(), splitList(*profiles))
+	rows := make([]interface{}, 0, len(listed))
+	for _, profile := range listed {
+		rows = append(rows, profile)
+	}
+
+	if err := writeRows(os.Stdout, *format, rows, listed); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitErrors
+	}
+	if len(listed) == 0 {
+		return exitNotFound
+	}
+	return exitOk
+}
+
+// profileRegion is a row of the regions command.
+type profileRegion struct {
+	Profile string `json:"profile"`
+	Region  string `json:"region"`
+}
+
+func runRegions(args []string) int {
+	flags := flag.NewFlagSet("regions", flag.ContinueOnError)
+	profiles := flags.String("profiles", "", "comma separated profile names or globs, e.g. prod-* (default every profile)")
+	regions := flags.String("regions", "", "comma separated region names or globs to list, e.g. eu-* (default every enabled region)")
+	format := flags.String("format", formatTable, "output format: table, json, ndjson or csv")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOk
+		}
+		return exitUsage
+	}
+	if !validFormat(*format) {
+		fmt.Fprintf(os.Stderr, "invalid format %q: expected table, json, ndjson or csv\n", *format)
+		return exitUsage
+	}
+
+	listed := search.FilterProfiles(loadProfiles(), splitList(*profiles))
+	regionsOfProfiles := make([][]string, len(listed))
+	errs := make([]error, len(listed))
+
+	var wg sync.WaitGroup
+	for i, profile := range listed {
+		wg.Add(1)
+		go func(i int, profile search.Profile) {
+			defer wg.Done()
+			cfg, err := profile.LoadConfig(context.Background())
+			if err != nil {
+				errs[i] = fmt.Errorf("profile %s: failed to load configuration: %w", profile.Name, err)
+				return
+			}
+			profileRegions, err := search.GetRegions(context.Background(), profile, cfg)
+			if err != nil {
+				errs[i] = fmt.Errorf("profile %s: failed to get regions: %w", profile.Name, err)
+			}
+			regionsOfProfiles[i] = search.FilterRegions(profileRegions, splitList(*regions))
+		}(i, profile)
+	}
+	wg.Wait()
+
+	rows := []interface{}{}
+	for i, profile := range listed {
+		for _, region := range regionsOfProfiles[i] {
+			rows = append(rows, profileRegion{Profile: profile.Name, Region: region})
+		}
+	}
+
+	if err := writeRows(os.Stdout, *format, rows, rows); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitErrors
+	}
+
+	failed := false
+	for _, err := range errs {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	switch {
+	case failed:
+		return exitErrors
+	case len(rows) == 0:
+		return exitNotFound
+	}
+	return exitOk
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aviadhaham/cloudcate/internal/config"
+	"github.com/aviadhaham/cloudcate/internal/search"
+	"github.com/aviadhaham/cloudcate/internal/services"
+)
+
+// Exit codes of the commands
+const (
+	exitOk       = 0
+	exitNotFound = 1
+	exitErrors   = 2
+	exitUsage    = 3
+)
+
+// parseInterspersed parses the flags of a command wherever they are among its positional arguments,
+// so "search ec2 10.0.4.12 --profiles prod-*" works as well as "search --profiles prod-* ec2 10.0.4.12".
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func runSearch(args []string) int {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cloudcate search <type> <term> [flags]\n       cloudcate search <type> --file <file> [flags]\n\n<type> is a resource type (see \"cloudcate search types\") or \"all\".\nThe exit code is %d when nothing was found and %d when some profiles or regions could not be searched.\n\nFlags:\n", exitNotFound, exitErrors)
+		flags.PrintDefaults()
+	}
+	profiles := flags.String("profiles", "", "comma separated profile names or globs to search, e.g. prod-* (default every profile)")
+	regions := flags.String("regions", "", "comma separated region names or globs to search, e.g. eu-* (default every enabled region)")
+	subType := flags.String("subtype", "", "resource subtype, e.g. key for iam (default every subtype)")
+	format := flags.String("format", formatTable, "output format: table, json, ndjson or csv")
+	maxPages := flags.Int("max-pages", config.DefaultMaxPages, "cap on the pages walked by every paginated AWS call, 0 for no cap")
+	timeout := flags.Duration("timeout", 0, "deadline for the whole search, e.g. 30s (default none)")
+	dedupeAccounts := flags.Bool("dedupe-accounts", false, "search every account once, through its first working profile")
+	match := flags.String("match", services.MatchSubstring, "how terms are matched: substring, exact, prefix, regex or glob")
+	caseSensitive := flags.Bool("case-sensitive", false, "match case-sensitively (every mode but substring)")
+	file := flags.String("file", "", "search every term of the file (one per line, - for stdin) at once, results are grouped by term")
+	verbose := flags.Bool("verbose", false, "log every AWS error as it happens, on top of the summary of errors")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOk
+		}
+		return exitUsage
+	}
+	if len(positional) == 1 && positional[0] == "types" {
+		return printResourceTypes()
+	}
+	if (*file == "" && len(positional) != 2) || (*file != "" && len(positional) != 1) {
+		flags.Usage()
+		return exitUsage
+	}
+	if !validFormat(*format) {
+		fmt.Fprintf(os.Stderr, "invalid format %q: expected table, json, ndjson or csv\n", *format)
+		return exitUsage
+	}
+
+	matchOptions := services.MatchOptions{Mode: *match, CaseSensitive: *caseSensitive}
+	var terms []string
+	if *file != "" {
+		terms, err = readTerms(*file, matchOptions)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		positional = append(positional, "")
+	}
+
+	query, err := search.NewQuery(positional[0], *subType, positional[1], matchOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	query.Terms = terms
+	query.MaxPages = *maxPages
+	query.DedupeAccounts = *dedupeAccounts
+	query.Regions = splitList(*regions)
+
+	searched := search.FilterProfiles(loadProfiles(), splitList(*profiles))
+	if len(searched) == 0 {
+		fmt.Fprintf(os.Stderr, "no profile matches %q\n", *profiles)
+		return exitUsage
+	}
+
+	// the errors are reported once the search is over, the server logs are only noise in a terminal
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	var results []search.Resource
+	var searchErrors []search.SearchError
+	var truncated []search.Truncation
+
+	if len(terms) > 0 {
+		response := search.FindResourcesBulk(ctx, searched, query)
+		if err := writeRows(os.Stdout, *format, termRows(response), response); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitErrors
+		}
+		for _, termResults := range response.Terms {
+			results = append(results, termResults.Results...)
+			if len(termResults.Results) == 0 && *format != formatJson {
+				fmt.Fprintf(os.Stderr, "no match: %s\n", termResults.Term)
+			}
+		}
+		searchErrors, truncated = response.Errors, response.Truncated
+	} else {
+		response := search.FindResources(ctx, searched, query)
+		if err := writeRows(os.Stdout, *format, resourceRows(response.Results), response); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitErrors
+		}
+		results, searchErrors, truncated = response.Results, response.Errors, response.Truncated
+	}
+
+	// errors go to stderr, so they never mix with the results piped elsewhere
+	if *format != formatJson {
+		for _, searchErr := range searchErrors {
+			fmt.Fprintf(os.Stderr, "error: profile %s, region %s, %s (%s): %s\n", searchErr.Profile, searchErr.Region, searchErr.Service, searchErr.Class, searchErr.Message)
+		}
+		for _, truncation := range truncated {
+			fmt.Fprintf(os.Stderr, "truncated: profile %s, region %s, %s: %s\n", truncation.Profile, truncation.Region, truncation.Service, truncation.Message)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d results in %d profiles (%s)\n", len(results), len(searched), time.Since(start).Round(time.Millisecond))
+
+	switch {
+	case len(searchErrors) > 0:
+		return exitErrors
+	case len(results) == 0:
+		return exitNotFound
+	}
+	return exitOk
+}
+
+// readTerms reads the terms of a bulk search, one per line, from a file or from stdin with "-".
+func readTerms(file string, match services.MatchOptions) ([]string, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terms: %w", err)
+	}
+
+	terms := search.UniqueTerms(strings.Split(string(data), "\n"))
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("no terms in %s", file)
+	}
+	for _, term := range terms {
+		if _, err := search.ParseTerm(term, match); err != nil {
+			return nil, fmt.Errorf("invalid term %q: %w", term, err)
+		}
+	}
+	return terms, nil
+}
+
+func resourceRows(resources []search.Resource) []interface{} {
+	rows := make([]interface{}, 0, len(resources))
+	for _, resource := range resources {
+		rows = append(rows, resource)
+	}
+	return rows
+}
+
+// termRows prefixes every result of a bulk search with the term it matched.
+func termRows(response search.BulkResponse) []interface{} {
+	rows := []interface{}{}
+	for _, termResults := range response.Terms {
+		for _, result := range termResults.Results {
+			data, err := json.Marshal(result)
+			if err != nil || len(data) < 2 || data[0] != '{' {
+				continue
+			}
+			term, _ := json.Marshal(termResults.Term)
+			row := append([]byte(`{"term":`), term...)
+			if len(data) > 2 {
+				row = append(row, ',')
+			}
+			rows = append(rows, json.RawMessage(append(row, data[1:]...)))
+		}
+	}
+	return rows
+}
+
+func printResourceTypes() int {
+	for _, searcher := range search.Searchers() {
+		if len(searcher.SubTypes()) > 0 {
+			fmt.Printf("%s (%s)\n", searcher.Type(), strings.Join(searcher.SubTypes(), ", "))
+		} else {
+			fmt.Println(searcher.Type())
+		}
+	}
+	return exitOk
+}
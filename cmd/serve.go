@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aviadhaham/cloudcate/internal/alert"
+	"github.com/aviadhaham/cloudcate/internal/config"
+	"github.com/aviadhaham/cloudcate/internal/index"
+	"github.com/aviadhaham/cloudcate/internal/search"
+	"github.com/aviadhaham/cloudcate/internal/web"
+)
+
+// runServe starts the web server, it is configured through environment variables.
+func runServe(args []string) {
+	if len(args) > 0 {
+		log.Fatalf("serve takes no arguments, it is configured through environment variables")
+	}
+
+	profiles := loadProfiles()
+
+	if os.Getenv("PORT") == "" {
+		log.Fatalf("PORT env var is not set")
+	}
+
+	// with INDEX_PATH set, every profile is crawled in the background and searches are answered from the index
+	var idx *index.Index
+	if indexPath := os.Getenv("INDEX_PATH"); indexPath != "" {
+		var err error
+		idx, err = index.Open(indexPath)
+		if err != nil {
+			log.Fatalf("Failed to open index: %v", err)
+		}
+		defer idx.Close()
+
+		interval := time.Hour
+		if os.Getenv("CRAWL_INTERVAL") != "" {
+			interval, err = time.ParseDuration(os.Getenv("CRAWL_INTERVAL"))
+			if err != nil || interval <= 0 {
+				log.Fatalf("Invalid CRAWL_INTERVAL %q: expected a duration such as 30m", os.Getenv("CRAWL_INTERVAL"))
+			}
+		}
+
+		retention := 30 * 24 * time.Hour
+		if os.Getenv("SNAPSHOT_RETENTION") != "" {
+			retention, err = time.ParseDuration(os.Getenv("SNAPSHOT_RETENTION"))
+			if err != nil || retention < 0 {
+				log.Fatalf("Invalid SNAPSHOT_RETENTION %q: expected a duration such as 720h", os.Getenv("SNAPSHOT_RETENTION"))
+			}
+		}
+
+		crawler := &search.Crawler{
+			Index:             idx,
+			Profiles:          profiles,
+			Interval:          interval,
+			MaxPages:          config.DefaultMaxPages,
+			SnapshotRetention: retention,
+		}
+		go crawler.Run(context.Background())
+
+		alertInterval := 15 * time.Minute
+		if os.Getenv("ALERT_INTERVAL") != "" {
+			alertInterval, err = time.ParseDuration(os.Getenv("ALERT_INTERVAL"))
+			if err != nil || alertInterval <= 0 {
+				log.Fatalf("Invalid ALERT_INTERVAL %q: expected a duration such as 15m", os.Getenv("ALERT_INTERVAL"))
+			}
+		}
+
+		evaluator := &alert.Evaluator{Index: idx, Profiles: profiles, Interval: alertInterval}
+		go evaluator.Run(context.Background())
+	}
+
+	s := web.NewServer(os.Getenv("PORT"), profiles, idx)
+	s.Run()
+}
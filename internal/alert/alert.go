@@ -0,0 +1,159 @@
+package alert
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/aviadhaham/cloudcate/internal/index"
+	"github.com/aviadhaham/cloudcate/internal/search"
+)
+
+// Evaluator periodically runs the saved searches and calls their webhooks when their result set changes.
+type Evaluator struct {
+	Index    *index.Index
+	Profiles []search.Profile
+	Interval time.Duration
+}
+
+// Change is what a saved search gained and lost since its last evaluation.
+type Change struct {
+	SavedSearch index.SavedSearch `json:"saved_search"`
+	Added       []json.RawMessage `json:"added"`
+	Removed     []json.RawMessage `json:"removed"`
+}
+
+// Run evaluates every saved search every Interval, until ctx is done.
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		savedSearches, err := e.Index.SavedSearches()
+		if err != nil {
+			log.Printf("error evaluating saved searches: %v", err)
+			continue
+		}
+		for _, savedSearch := range savedSearches {
+			e.Evaluate(ctx, savedSearch)
+		}
+	}
+}
+
+// Evaluate runs a saved search, calls its webhook if its result set changed and stores the new results.
+// The first evaluation only records the results. Results of accounts that could not be searched are not
+// reported as removed, they are kept until the account can be searched again.
+func (e *Evaluator) Evaluate(ctx context.Context, savedSearch index.SavedSearch) {
+	now := time.Now()
+	savedSearch.LastEvaluatedAt = &now
+	savedSearch.LastError = ""
+
+	results, complete, err := e.search(ctx, savedSearch)
+	if err != nil {
+		savedSearch.LastError = err.Error()
+		e.save(savedSearch)
+		return
+	}
+	e.record(ctx, savedSearch, results, complete)
+}
+
+// record compares the results of an evaluation with the previous ones, calls the webhook if resources were added
+// or removed and stores the new results. A resource whose fields changed (state, tags...) is still the same member
+// of the result set, so it is stored with its new fields without calling the webhook.
+func (e *Evaluator) record(ctx context.Context, savedSearch index.SavedSearch, results map[string]json.RawMessage, complete bool) {
+	previous := savedSearch.Results
+
+	change := Change{SavedSearch: savedSearch, Added: []json.RawMessage{}, Removed: []json.RawMessage{}}
+	for fingerprint, result := range results {
+		if _, existed := previous[fingerprint]; !existed {
+			change.Added = append(change.Added, result)
+		}
+	}
+	for fingerprint, result := range previous {
+		if _, exists := results[fingerprint]; exists {
+			continue
+		}
+		if complete {
+			change.Removed = append(change.Removed, result)
+		} else {
+			results[fingerprint] = result
+		}
+	}
+
+	// the first evaluation is the baseline
+	firstEvaluation := savedSearch.Results == nil
+	if !firstEvaluation && (len(change.Added) > 0 || len(change.Removed) > 0) {
+		if err := sendWebhook(ctx, savedSearch, change); err != nil {
+			log.Printf("saved search '%s': %v", savedSearch.Name, err)
+			savedSearch.LastError = err.Error()
+			// keep the previous results, so the change is reported again by the next evaluation
+			e.save(savedSearch)
+			return
+		}
+	}
+
+	savedSearch.Results = results
+	e.save(savedSearch)
+}
+
+// search runs the saved search and returns its results by fingerprint, and whether every profile and
+// region could be searched.
+func (e *Evaluator) search(ctx context.Context, savedSearch index.SavedSearch) (map[string]json.RawMessage, bool, error) {
+	query, err := search.NewQuery(savedSearch.ResourceType, savedSearch.ResourceSubType, savedSearch.ResourceName, savedSearch.MatchOptions())
+	if err != nil {
+		return nil, false, err
+	}
+	query.Index = e.Index
+
+	response := search.FindResources(ctx, search.FilterProfiles(e.Profiles, savedSearch.Profiles), query)
+
+	results, err := resultsByFingerprint(response.Results)
+	if err != nil {
+		return nil, false, err
+	}
+	return results, len(response.Errors) == 0 && len(response.Truncated) == 0, nil
+}
+
+// resultsByFingerprint marshals the results of a search by their fingerprint.
+func resultsByFingerprint(resources []search.Resource) (map[string]json.RawMessage, error) {
+	results := map[string]json.RawMessage{}
+	for _, result := range resources {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		results[fingerprint(result)] = data
+	}
+	return results, nil
+}
+
+func (e *Evaluator) save(savedSearch index.SavedSearch) {
+	// the saved search may have been deleted while it was evaluated
+	if _, found, err := e.Index.SavedSearch(savedSearch.Id); err != nil || !found {
+		return
+	}
+	if err := e.Index.PutSavedSearch(savedSearch); err != nil {
+		log.Printf("saved search '%s': error storing its results: %v", savedSearch.Name, err)
+	}
+}
+
+// fingerprint identifies a result by the resource it is, not by its fields: a resource whose state or tags
+// changed is still the same member of the result set.
+func fingerprint(result search.Resource) string {
+	id := result.Id
+	if id == "" {
+		id = result.Arn
+	}
+	identity, _ := json.Marshal([]string{result.AccountId, result.ResourceType, result.ResourceSubType, result.Region, id})
+	sum := sha256.Sum256(identity)
+	return hex.EncodeToString(sum[:])
+}
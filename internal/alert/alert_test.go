@@ -0,0 +1,90 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aviadhaham/cloudcate/internal/index"
+	"github.com/aviadhaham/cloudcate/internal/search"
+)
+
+func TestRecordIgnoresChangedFieldsOfMatchedResources(t *testing.T) {
+	idx, err := index.Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	var calls int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer webhook.Close()
+
+	savedSearch := index.SavedSearch{Id: "s1", Name: "web instances", ResourceType: "ec2", ResourceName: "web", WebhookUrl: webhook.URL}
+	if err := idx.PutSavedSearch(savedSearch); err != nil {
+		t.Fatal(err)
+	}
+	e := &Evaluator{Index: idx}
+
+	instance := search.Resource{
+		ResourceType: "ec2",
+		Id:           "i-0abc",
+		AccountId:    "123456789012",
+		Profile:      "prod",
+		Region:       "eu-west-1",
+		Tags:         map[string]string{"Owner": "team-a"},
+		Attributes:   search.Ec2Attributes{InstanceId: "i-0abc", State: "running"},
+	}
+	evaluate := func(resources ...search.Resource) index.SavedSearch {
+		t.Helper()
+		stored, _, err := idx.SavedSearch(savedSearch.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := resultsByFingerprint(resources)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.record(context.Background(), stored, results, true)
+		stored, _, err = idx.SavedSearch(savedSearch.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+
+	// the first evaluation is the baseline
+	evaluate(instance)
+
+	instance.Tags = map[string]string{"Owner": "team-b"}
+	instance.Attributes = search.Ec2Attributes{InstanceId: "i-0abc", State: "stopped"}
+	stored := evaluate(instance)
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Fatalf("webhook called %d times for a matched resource whose tags changed, want 0", n)
+	}
+	if len(stored.Results) != 1 {
+		t.Fatalf("stored %d results, want 1", len(stored.Results))
+	}
+	for _, data := range stored.Results {
+		var result search.Resource
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Tags["Owner"] != "team-b" {
+			t.Errorf("stored tags = %v, want the new tags", result.Tags)
+		}
+	}
+
+	other := instance
+	other.Id = "i-0def"
+	evaluate(instance, other)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("webhook called %d times for an added resource, want 1", n)
+	}
+}
@@ -0,0 +1,62 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aviadhaham/cloudcate/internal/index"
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// slackMessage is the payload of a Slack incoming webhook.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// sendWebhook posts the change of a saved search to its webhook, either as a Slack message or as the JSON of the change.
+func sendWebhook(ctx context.Context, savedSearch index.SavedSearch, change Change) error {
+	var payload interface{} = change
+	if savedSearch.WebhookFormat == index.WebhookFormatSlack {
+		payload = slackMessage{Text: slackText(change)}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, savedSearch.WebhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+func slackText(change Change) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*%s*: %d added, %d removed", change.SavedSearch.Name, len(change.Added), len(change.Removed))
+	for _, result := range change.Added {
+		fmt.Fprintf(&text, "\n+ `%s`", result)
+	}
+	for _, result := range change.Removed {
+		fmt.Fprintf(&text, "\n- `%s`", result)
+	}
+	return text.String()
+}
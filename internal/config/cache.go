@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is how long the listing of a resource type in a profile and region is reused by
+// later searches, unless the resource type has its own TTL in CacheTTLs.
+var DefaultCacheTTL = 5 * time.Minute
+
+// CacheTTLs overrides DefaultCacheTTL per resource type, slowly changing types are kept longer.
+var CacheTTLs = map[string]time.Duration{
+	"iam":        15 * time.Minute,
+	"s3":         15 * time.Minute,
+	"cloudfront": 15 * time.Minute,
+	"dns":        10 * time.Minute,
+}
+
+// ProfileCacheTTL is how long the loaded configuration and the enabled regions of a profile are reused.
+var ProfileCacheTTL = time.Hour
+
+// CacheTTL returns the cache TTL of a resource type.
+func CacheTTL(resourceType string) time.Duration {
+	if ttl, ok := CacheTTLs[resourceType]; ok {
+		return ttl
+	}
+	return DefaultCacheTTL
+}
+
+// ParseCacheTTLs parses a comma separated list of resource type TTLs, e.g. "ec2=1m,s3=1h", into CacheTTLs.
+// The "default" key sets DefaultCacheTTL.
+func ParseCacheTTLs(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		resourceType, rawTTL, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("invalid cache TTL %q: expected <resource type>=<duration>", entry)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(rawTTL))
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("invalid cache TTL %q: expected a duration like 5m", entry)
+		}
+		resourceType = strings.TrimSpace(resourceType)
+		if resourceType == "default" {
+			DefaultCacheTTL = ttl
+		} else {
+			CacheTTLs[resourceType] = ttl
+		}
+	}
+	return nil
+}
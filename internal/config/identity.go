@@ -0,0 +1,7 @@
+package config
+
+import "time"
+
+// IdentityTTL is how long the resolved identity of a profile (account ID, alias and name) is cached
+// before it is looked up again.
+var IdentityTTL = time.Hour
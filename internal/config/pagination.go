@@ -0,0 +1,5 @@
+package config
+
+// DefaultMaxPages caps how many pages every paginated AWS call walks when a search doesn't set max_pages.
+// It keeps a single huge account from stalling a search, truncated listings are reported in the response.
+var DefaultMaxPages = 100
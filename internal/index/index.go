@@ -0,0 +1,149 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	listingsBucket = []byte("listings")
+	statusBucket   = []byte("crawl_status")
+)
+
+// Scope identifies one crawled listing: a resource type (and subtype) in a profile and region.
+type Scope struct {
+	Profile         string `json:"profile"`
+	Region          string `json:"region"`
+	ResourceType    string `json:"resource_type"`
+	ResourceSubType string `json:"resource_subtype,omitempty"`
+}
+
+func (s Scope) key() []byte {
+	return []byte(strings.Join([]string{s.Profile, s.Region, s.ResourceType, s.ResourceSubType}, "\x00"))
+}
+
+// Listing is the stored outcome of listing a scope, the resources are kept as the JSON the searcher decodes.
+type Listing struct {
+	Scope     Scope           `json:"scope"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Truncated bool            `json:"truncated"`
+	Resources json.RawMessage `json:"resources"`
+}
+
+// Crawl statuses
+const (
+	StatusOk        = "ok"
+	StatusTruncated = "truncated"
+	StatusFailed    = "failed"
+)
+
+// CrawlStatus records the last crawl of a scope.
+type CrawlStatus struct {
+	Scope
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Duration  int64     `json:"duration_ms"`
+	// Count is the number of resources listed
+	Count int `json:"count"`
+}
+
+// Index is the local inventory the crawler fills and the searches read from, stored in a bbolt database.
+type Index struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the index database at path.
+func Open(path string) (*Index, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{listingsBucket, statusBucket, snapshotsBucket, snapshotScopesBucket, savedSearchesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize index %s: %w", path, err)
+	}
+
+	return &Index{db: db}, nil
+}
+
+func (i *Index) Close() error {
+	return i.db.Close()
+}
+
+// PutListing stores the listing of a scope, replacing the previous one.
+func (i *Index) PutListing(listing Listing) error {
+	return i.put(listingsBucket, listing.Scope.key(), listing)
+}
+
+// Listing returns the stored listing of a scope, false if the scope was never crawled.
+func (i *Index) Listing(scope Scope) (Listing, bool, error) {
+	var listing Listing
+	found, err := i.get(listingsBucket, scope.key(), &listing)
+	return listing, found, err
+}
+
+// PutStatus records the last crawl of a scope.
+func (i *Index) PutStatus(status CrawlStatus) error {
+	return i.put(statusBucket, status.Scope.key(), status)
+}
+
+// Statuses returns the last crawl status of every crawled scope.
+func (i *Index) Statuses() ([]CrawlStatus, error) {
+	statuses := []CrawlStatus{}
+	err := i.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(statusBucket).ForEach(func(_, value []byte) error {
+			var status CrawlStatus
+			if err := json.Unmarshal(value, &status); err != nil {
+				return err
+			}
+			statuses = append(statuses, status)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read crawl statuses: %w", err)
+	}
+	return statuses, nil
+}
+
+func (i *Index) put(bucket []byte, key []byte, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return i.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, data)
+	})
+}
+
+func (i *Index) get(bucket []byte, key []byte, value interface{}) (bool, error) {
+	var data []byte
+	err := i.db.View(func(tx *bolt.Tx) error {
+		// the value is only valid during the transaction
+		if stored := tx.Bucket(bucket).Get(key); stored != nil {
+			data = append([]byte{}, stored...)
+		}
+		return nil
+	})
+	if err != nil || data == nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, fmt.Errorf("failed to decode index entry: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,97 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aviadhaham/cloudcate/internal/services"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var savedSearchesBucket = []byte("saved_searches")
+
+// Webhook formats
+const (
+	WebhookFormatSlack = "slack"
+	WebhookFormatJson  = "json"
+)
+
+// SavedSearch is a query evaluated periodically, its webhook is called when its result set changes.
+type SavedSearch struct {
+	Id              string `json:"id"`
+	Name            string `json:"name"`
+	ResourceType    string `json:"resource_type"`
+	ResourceSubType string `json:"resource_subtype,omitempty"`
+	ResourceName    string `json:"resource_name"`
+	// Match and CaseSensitive are the match options of the search (see services.MatchOptions)
+	Match         string `json:"match,omitempty"`
+	CaseSensitive bool   `json:"case_sensitive,omitempty"`
+	// Profiles are the names (or glob patterns, e.g. prod-*) of the searched profiles, every profile when empty
+	Profiles      []string  `json:"profiles,omitempty"`
+	WebhookUrl    string    `json:"webhook_url"`
+	WebhookFormat string    `json:"webhook_format"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// LastEvaluatedAt and LastError describe the last evaluation
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	// Results are the results of the last evaluation by fingerprint
+	Results map[string]json.RawMessage `json:"-"`
+}
+
+// MatchOptions returns how the saved search compares its name with the resources.
+func (s SavedSearch) MatchOptions() services.MatchOptions {
+	return services.MatchOptions{Mode: s.Match, CaseSensitive: s.CaseSensitive}
+}
+
+// storedSavedSearch also persists the last results, which the API doesn't expose.
+type storedSavedSearch struct {
+	SavedSearch
+	Results map[string]json.RawMessage `json:"results"`
+}
+
+// PutSavedSearch creates or replaces a saved search.
+func (i *Index) PutSavedSearch(savedSearch SavedSearch) error {
+	return i.put(savedSearchesBucket, []byte(savedSearch.Id), storedSavedSearch{SavedSearch: savedSearch, Results: savedSearch.Results})
+}
+
+// SavedSearch returns the saved search with the given ID, false if there is none.
+func (i *Index) SavedSearch(id string) (SavedSearch, bool, error) {
+	var stored storedSavedSearch
+	found, err := i.get(savedSearchesBucket, []byte(id), &stored)
+	stored.SavedSearch.Results = stored.Results
+	return stored.SavedSearch, found, err
+}
+
+// SavedSearches returns every saved search.
+func (i *Index) SavedSearches() ([]SavedSearch, error) {
+	savedSearches := []SavedSearch{}
+	err := i.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(savedSearchesBucket).ForEach(func(_, value []byte) error {
+			var stored storedSavedSearch
+			if err := json.Unmarshal(value, &stored); err != nil {
+				return err
+			}
+			stored.SavedSearch.Results = stored.Results
+			savedSearches = append(savedSearches, stored.SavedSearch)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved searches: %w", err)
+	}
+	return savedSearches, nil
+}
+
+// DeleteSavedSearch deletes a saved search, false if there was none with the given ID.
+func (i *Index) DeleteSavedSearch(id string) (bool, error) {
+	found := false
+	err := i.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(savedSearchesBucket)
+		found = bucket.Get([]byte(id)) != nil
+		return bucket.Delete([]byte(id))
+	})
+	return found, err
+}
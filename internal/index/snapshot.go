@@ -0,0 +1,284 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	snapshotsBucket      = []byte("snapshots")
+	snapshotScopesBucket = []byte("snapshot_scopes")
+)
+
+// Snapshot is the inventory collected by one crawl.
+type Snapshot struct {
+	Id         int64     `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+func (s Snapshot) key() []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(s.Id))
+	return key
+}
+
+// ScopeResources are the resources of a scope in a snapshot, by resource ID, in their search result form.
+type ScopeResources struct {
+	Scope     Scope                      `json:"scope"`
+	Resources map[string]json.RawMessage `json:"resources"`
+}
+
+// Change kinds
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// Change is a resource that was added, removed or modified between two snapshots.
+type Change struct {
+	Scope
+	Change     string `json:"change"`
+	ResourceId string `json:"resource_id"`
+	// ChangedFields lists the result fields that differ, for modified resources
+	ChangedFields []string        `json:"changed_fields,omitempty"`
+	Before        json.RawMessage `json:"before,omitempty"`
+	After         json.RawMessage `json:"after,omitempty"`
+}
+
+// ChangeFilter narrows the compared scopes, empty fields match every scope.
+type ChangeFilter struct {
+	Profile      string
+	Region       string
+	ResourceType string
+}
+
+func (f ChangeFilter) matches(scope Scope) bool {
+	return (f.Profile == "" || f.Profile == scope.Profile) &&
+		(f.Region == "" || f.Region == scope.Region) &&
+		(f.ResourceType == "" || f.ResourceType == scope.ResourceType)
+}
+
+// PutSnapshot stores the resources collected by a crawl as a new snapshot. The scopes of the previous
+// snapshot that the crawl didn't collect (because they failed) keep their previous resources, so a failing
+// scope doesn't show up as every one of its resources being removed.
+func (i *Index) PutSnapshot(snapshot Snapshot, scopes []ScopeResources) error {
+	return i.db.Update(func(tx *bolt.Tx) error {
+		snapshots := tx.Bucket(snapshotsBucket)
+		allScopes := tx.Bucket(snapshotScopesBucket)
+
+		previousKey, _ := snapshots.Cursor().Last()
+
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		if err := snapshots.Put(snapshot.key(), data); err != nil {
+			return err
+		}
+
+		snapshotScopes, err := allScopes.CreateBucket(snapshot.key())
+		if err != nil {
+			return fmt.Errorf("failed to store snapshot %d: %w", snapshot.Id, err)
+		}
+		for _, scope := range scopes {
+			data, err := json.Marshal(scope)
+			if err != nil {
+				return err
+			}
+			if err := snapshotScopes.Put(scope.Scope.key(), data); err != nil {
+				return err
+			}
+		}
+
+		if previousKey == nil {
+			return nil
+		}
+		previousScopes := allScopes.Bucket(previousKey)
+		if previousScopes == nil {
+			return nil
+		}
+		return previousScopes.ForEach(func(key, value []byte) error {
+			if snapshotScopes.Get(key) != nil {
+				return nil
+			}
+			return snapshotScopes.Put(key, append([]byte{}, value...))
+		})
+	})
+}
+
+// Snapshots returns every stored snapshot, oldest first.
+func (i *Index) Snapshots() ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+	err := i.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(snapshotsBucket).ForEach(func(_, value []byte) error {
+			var snapshot Snapshot
+			if err := json.Unmarshal(value, &snapshot); err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshot)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// SnapshotAt returns the latest snapshot started at or before t, or the earliest snapshot when they all
+// started after it. It returns false when there are no snapshots yet.
+func (i *Index) SnapshotAt(t time.Time) (Snapshot, bool, error) {
+	snapshots, err := i.Snapshots()
+	if err != nil || len(snapshots) == 0 {
+		return Snapshot{}, false, err
+	}
+
+	found := snapshots[0]
+	for _, snapshot := range snapshots {
+		if snapshot.StartedAt.After(t) {
+			break
+		}
+		found = snapshot
+	}
+	return found, true, nil
+}
+
+// PruneSnapshots deletes the snapshots started before the given time, the latest snapshot is always kept.
+func (i *Index) PruneSnapshots(before time.Time) error {
+	snapshots, err := i.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	return i.db.Update(func(tx *bolt.Tx) error {
+		for n, snapshot := range snapshots {
+			if n == len(snapshots)-1 || !snapshot.StartedAt.Before(before) {
+				break
+			}
+			if err := tx.Bucket(snapshotsBucket).Delete(snapshot.key()); err != nil {
+				return err
+			}
+			if err := tx.Bucket(snapshotScopesBucket).DeleteBucket(snapshot.key()); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Changes lists the resources added, removed or modified from one snapshot to another.
+func (i *Index) Changes(from Snapshot, to Snapshot, filter ChangeFilter) ([]Change, error) {
+	fromScopes, err := i.snapshotScopes(from, filter)
+	if err != nil {
+		return nil, err
+	}
+	toScopes, err := i.snapshotScopes(to, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	for key, after := range toScopes {
+		before := fromScopes[key]
+		for id, afterResource := range after.Resources {
+			beforeResource, existed := before.Resources[id]
+			switch {
+			case !existed:
+				changes = append(changes, Change{Scope: after.Scope, Change: ChangeAdded, ResourceId: id, After: afterResource})
+			case !bytes.Equal(beforeResource, afterResource):
+				changedFields, err := changedFields(beforeResource, afterResource)
+				if err != nil {
+					return nil, err
+				}
+				if len(changedFields) > 0 {
+					changes = append(changes, Change{Scope: after.Scope, Change: ChangeModified, ResourceId: id, ChangedFields: changedFields, Before: beforeResource, After: afterResource})
+				}
+			}
+		}
+	}
+	for key, before := range fromScopes {
+		after := toScopes[key]
+		for id, beforeResource := range before.Resources {
+			if _, exists := after.Resources[id]; !exists {
+				changes = append(changes, Change{Scope: before.Scope, Change: ChangeRemoved, ResourceId: id, Before: beforeResource})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(a, b int) bool {
+		if changes[a].Scope != changes[b].Scope {
+			return string(changes[a].Scope.key()) < string(changes[b].Scope.key())
+		}
+		return changes[a].ResourceId < changes[b].ResourceId
+	})
+	return changes, nil
+}
+
+func (i *Index) snapshotScopes(snapshot Snapshot, filter ChangeFilter) (map[string]ScopeResources, error) {
+	scopes := map[string]ScopeResources{}
+	err := i.db.View(func(tx *bolt.Tx) error {
+		snapshotScopes := tx.Bucket(snapshotScopesBucket).Bucket(snapshot.key())
+		if snapshotScopes == nil {
+			return fmt.Errorf("snapshot %d was not found", snapshot.Id)
+		}
+		return snapshotScopes.ForEach(func(key, value []byte) error {
+			var scope ScopeResources
+			if err := json.Unmarshal(value, &scope); err != nil {
+				return err
+			}
+			if filter.matches(scope.Scope) {
+				scopes[string(key)] = scope
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %d: %w", snapshot.Id, err)
+	}
+	return scopes, nil
+}
+
+// changedFields compares two search results field by field. Nested objects, such as the attributes and
+// the tags of a resource, are compared by their own fields, e.g. attributes.public_ip_address.
+func changedFields(before json.RawMessage, after json.RawMessage) ([]string, error) {
+	var beforeFields, afterFields map[string]interface{}
+	if err := json.Unmarshal(before, &beforeFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &afterFields); err != nil {
+		return nil, err
+	}
+
+	changed := compareFields("", beforeFields, afterFields)
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func compareFields(prefix string, beforeFields map[string]interface{}, afterFields map[string]interface{}) []string {
+	changed := []string{}
+	for field, value := range afterFields {
+		beforeObject, beforeIsObject := beforeFields[field].(map[string]interface{})
+		afterObject, afterIsObject := value.(map[string]interface{})
+		switch {
+		case beforeIsObject && afterIsObject:
+			changed = append(changed, compareFields(prefix+field+".", beforeObject, afterObject)...)
+		case !reflect.DeepEqual(beforeFields[field], value):
+			changed = append(changed, prefix+field)
+		}
+	}
+	for field := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			changed = append(changed, prefix+field)
+		}
+	}
+	return changed
+}
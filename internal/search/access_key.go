@@ -0,0 +1,91 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aviadhaham/cloudcate/internal/services"
+)
+
+// AccessKeyResponse is the account owning an access key and, when one of the profiles belongs to that account,
+// the key itself, along with the profiles that failed to look it up.
+type AccessKeyResponse struct {
+	AccessKeyId string        `json:"access_key_id"`
+	AccountId   string        `json:"account_id,omitempty"`
+	Resource    *Resource     `json:"resource,omitempty"`
+	Errors      []SearchError `json:"errors"`
+}
+
+// FindAccessKey looks an access key up by its ID. The account owning the key is resolved first (any profile can
+// tell it), then only the profiles of that account are asked for the key, instead of listing the keys of every
+// user of every profile. It returns a *NoProfileError when no profile belongs to the account, with the account
+// in the response, and an error wrapping services.ErrNotFound when the account no longer has the key.
+// raw adds the key as returned by AWS to the result.
+func FindAccessKey(ctx context.Context, profiles []Profile, accessKeyId string, raw bool) (AccessKeyResponse, error) {
+	response := AccessKeyResponse{AccessKeyId: accessKeyId, Errors: []SearchError{}}
+	searcher, _ := Lookup("iam")
+	target := scanTarget{searcher: searcher, resourceSubType: "key"}
+
+	scopes := []profileScope{}
+	for _, scope := range resolveProfiles(ctx, profiles, false) {
+		if scope.err != nil {
+			response.Errors = append(response.Errors, *scope.err)
+			continue
+		}
+		scopes = append(scopes, scope)
+	}
+
+	for _, scope := range scopes {
+		accountId, err := services.GetAccessKeyAccount(ctx, scope.cfg, scope.regions[0], accessKeyId)
+		if err != nil {
+			log.Printf("profile '%s': %v", scope.profile.Name, err)
+			response.Errors = append(response.Errors, newSearchError(scope.resultScope(scope.regions[0], target), "sts", err))
+			continue
+		}
+		response.AccountId = accountId
+		break
+	}
+	if response.AccountId == "" {
+		if len(scopes) == 0 {
+			return response, errors.New("no profile could be loaded")
+		}
+		// every profile failed to resolve the account, the errors tell why
+		return response, nil
+	}
+
+	matching := []profileScope{}
+	for _, scope := range scopes {
+		if scope.account.AccountId == response.AccountId {
+			matching = append(matching, scope)
+		}
+	}
+	if len(matching) == 0 {
+		return response, &NoProfileError{AccountId: response.AccountId}
+	}
+
+	for _, profile := range matching {
+		// IAM is global, it is called in the first region of the profile like the searches do
+		scope := profile.resultScope(profile.regions[0], target)
+
+		key, err := services.GetIamUserKey(ctx, profile.cfg, scope.Region, accessKeyId)
+		if errors.Is(err, services.ErrNotFound) {
+			return response, fmt.Errorf("account %s: %w", response.AccountId, err)
+		}
+		if err != nil {
+			log.Printf("profile '%s', error looking up access key %s: %v", profile.profile.Name, accessKeyId, err)
+			response.Errors = append(response.Errors, newSearchError(scope, searcher.Type(), err))
+			continue
+		}
+
+		result := searcher.Result(key, scope)
+		result.Profiles = accountProfiles(matching, response.AccountId)
+		if raw {
+			result.Raw = key
+		}
+		response.Resource = &result
+		return response, nil
+	}
+	return response, nil
+}
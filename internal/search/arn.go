@@ -0,0 +1,126 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aviadhaham/cloudcate/internal/services"
+)
+
+// ArnResponse is the resource an ARN identifies, along with the profiles that failed to describe it.
+type ArnResponse struct {
+	Arn      services.ResourceArn `json:"arn"`
+	Resource *Resource            `json:"resource,omitempty"`
+	Errors   []SearchError        `json:"errors"`
+}
+
+// NoProfileError is returned when none of the profiles belongs to the account of an ARN.
+type NoProfileError struct {
+	AccountId string
+}
+
+func (e *NoProfileError) Error() string {
+	return fmt.Sprintf("no profile has access to account %s", e.AccountId)
+}
+
+// arnDescriber returns the searcher describing the resources of an ARN, and the subtype of the resource.
+// The tags searcher, which can look up the resources of any service, comes last.
+func arnDescriber(resourceArn services.ResourceArn) (Searcher, string) {
+	var fallback Searcher
+	for _, searcher := range Searchers() {
+		describer, ok := searcher.(ArnDescriber)
+		if !ok {
+			continue
+		}
+		if _, isTags := searcher.(tagsSearcher); isTags {
+			fallback = searcher
+			continue
+		}
+		if resourceSubType, ok := describer.DescribesArn(resourceArn); ok {
+			return searcher, resourceSubType
+		}
+	}
+	return fallback, ""
+}
+
+// DescribeArn describes the resource an ARN identifies through the profiles of its account, the first profile
+// that describes it wins. ARNs without an account, such as the ones of S3 buckets or Route 53 hosted zones, are
+// looked up in every profile, and ARNs without a region in the global region of their partition.
+// It returns a *NoProfileError when no profile belongs to the account, and an error wrapping services.ErrNotFound
+// when the profiles of the account could describe the resource but it doesn't exist. raw adds the resource as
+// returned by AWS to the result.
+func DescribeArn(ctx context.Context, profiles []Profile, resourceArn services.ResourceArn, raw bool) (ArnResponse, error) {
+	response := ArnResponse{Arn: resourceArn, Errors: []SearchError{}}
+
+	searcher, resourceSubType := arnDescriber(resourceArn)
+	if searcher == nil {
+		return response, fmt.Errorf("no resource type describes %s resources", resourceArn.Service)
+	}
+	target := scanTarget{searcher: searcher, resourceSubType: resourceSubType}
+
+	matching := []profileScope{}
+	for _, scope := range resolveProfiles(ctx, profiles, false) {
+		if scope.err != nil {
+			response.Errors = append(response.Errors, *scope.err)
+			continue
+		}
+		if resourceArn.AccountId == "" || scope.account.AccountId == resourceArn.AccountId {
+			matching = append(matching, scope)
+		}
+	}
+	if len(matching) == 0 {
+		return response, &NoProfileError{AccountId: resourceArn.AccountId}
+	}
+
+	loadErrors := len(response.Errors)
+	// global resources have no region in their ARN, they are described where their service is served from
+	region := resourceArn.Region
+	if region == "" {
+		region = globalRegionOf(resourceArn.Partition)
+	}
+
+	for _, profile := range matching {
+		scope := profile.resultScope(region, target)
+
+		resource, err := searcher.(ArnDescriber).DescribeArn(ctx, profile.cfg, region, resourceArn)
+		if errors.Is(err, services.ErrNotFound) {
+			// another profile of the account wouldn't find it either, unless the ARN has no account
+			if resourceArn.AccountId != "" {
+				return response, err
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("profile '%s', error describing %s: %v", profile.profile.Name, resourceArn.Arn, err)
+			response.Errors = append(response.Errors, newSearchError(scope, searcher.Type(), err))
+			continue
+		}
+
+		result := searcher.Result(resource, scope)
+		result.Profiles = accountProfiles(matching, profile.account.AccountId)
+		if raw {
+			result.Raw = resource
+		}
+		response.Resource = &result
+		return response, nil
+	}
+
+	// none of the profiles failed, the resource doesn't exist
+	if len(response.Errors) == loadErrors {
+		return response, fmt.Errorf("%s: %w", resourceArn.Arn, services.ErrNotFound)
+	}
+	return response, nil
+}
+
+// accountProfiles returns the names of the profiles of an account.
+func accountProfiles(scopes []profileScope, accountId string) []string {
+	names := []string{}
+	for _, scope := range scopes {
+		if scope.account.AccountId == accountId {
+			names = append(names, scope.profile.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aviadhaham/cloudcate/internal/config"
+	"github.com/aviadhaham/cloudcate/internal/services"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// listingKey identifies a cached listing: one resource type (and subtype) in one profile and region.
+type listingKey struct {
+	profile         string
+	region          string
+	resourceType    string
+	resourceSubType string
+}
+
+type cachedListing struct {
+	resources []interface{}
+	fetchedAt time.Time
+}
+
+type cachedProfile struct {
+	cfg      aws.Config
+	regions  []string
+	loadedAt time.Time
+}
+
+var (
+	cacheMu        sync.RWMutex
+	listingCache   = map[listingKey]cachedListing{}
+	profileConfigs = map[string]cachedProfile{}
+)
+
+// fetchListing returns the resources of a scan target, from the index when the query reads from it and the
+// target was crawled, or from the cache when a fresh enough listing of it exists. Only complete listings are
+// cached, a failed or truncated one is fetched again by the next search.
+func fetchListing(ctx context.Context, profile profileScope, region string, target scanTarget, query Query) ([]interface{}, time.Time, error) {
+	if query.Index != nil && !query.Refresh {
+		if resources, listing, ok := indexedListing(query.Index, profile, region, target); ok {
+			if listing.Truncated {
+				return resources, listing.FetchedAt, fmt.Errorf("indexed listing was truncated when crawled: %w", services.ErrTruncated)
+			}
+			return resources, listing.FetchedAt, nil
+		}
+	}
+
+	key := listingKey{
+		profile:         profile.profile.Name,
+		region:          region,
+		resourceType:    target.searcher.Type(),
+		resourceSubType: target.resourceSubType,
+	}
+
+	if !query.Refresh {
+		cacheMu.RLock()
+		cached, ok := listingCache[key]
+		cacheMu.RUnlock()
+		if ok && time.Since(cached.fetchedAt) < config.CacheTTL(key.resourceType) {
+			return cached.resources, cached.fetchedAt, nil
+		}
+	}
+
+	fetchedAt := time.Now()
+	resources, err := target.searcher.Fetch(ctx, profile.cfg, region, target.resourceSubType, query.MaxPages)
+	if err == nil && config.CacheTTL(key.resourceType) > 0 {
+		cacheMu.Lock()
+		listingCache[key] = cachedListing{resources: resources, fetchedAt: fetchedAt}
+		cacheMu.Unlock()
+	}
+	return resources, fetchedAt, err
+}
+
+// loadProfile returns the configuration and the regions of a profile, loading them only when they
+// aren't cached yet (or when the search asks for fresh data).
+func loadProfile(ctx context.Context, profile Profile, refresh bool) (aws.Config, []string, error) {
+	if !refresh {
+		cacheMu.RLock()
+		cached, ok := profileConfigs[profile.Name]
+		cacheMu.RUnlock()
+		if ok && time.Since(cached.loadedAt) < config.ProfileCacheTTL {
+			return cached.cfg, cached.regions, nil
+		}
+	}
+
+	cfg, err := profile.LoadConfig(ctx)
+	if err != nil {
+		return aws.Config{}, nil, fmt.Errorf("failed to load configuration for profile: %w", err)
+	}
+
+	regions, err := GetRegions(ctx, profile, cfg)
+	if err != nil {
+		return aws.Config{}, nil, fmt.Errorf("failed to get regions: %w", err)
+	}
+
+	cacheMu.Lock()
+	profileConfigs[profile.Name] = cachedProfile{cfg: cfg, regions: regions, loadedAt: time.Now()}
+	cacheMu.Unlock()
+
+	return cfg, regions, nil
+}
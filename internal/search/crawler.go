@@ -0,0 +1,195 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aviadhaham/cloudcate/internal/index"
+)
+
+// crawlConcurrency caps the listings a crawl runs at once, so crawling doesn't starve live searches.
+const crawlConcurrency = 16
+
+// Crawler periodically lists every resource type in every profile and region into the index.
+type Crawler struct {
+	Index    *index.Index
+	Profiles []Profile
+	// Interval is the time between the start of two crawls
+	Interval time.Duration
+	// MaxPages caps the pages walked by every paginated AWS call, zero means no cap.
+	MaxPages int
+	// SnapshotRetention is how long crawl snapshots are kept, zero keeps them forever.
+	SnapshotRetention time.Duration
+}
+
+// Run crawls right away and then every Interval, until ctx is done.
+func (c *Crawler) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		c.Crawl(ctx)
+		log.Printf("crawl of %d profiles done in %s", len(c.Profiles), time.Since(start).Round(time.Millisecond))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Crawl lists every indexable resource type in every profile and region once, stores the listings
+// and the status of every scope in the index, and records the collected resources as a new snapshot.
+func (c *Crawler) Crawl(ctx context.Context) {
+	snapshot := index.Snapshot{StartedAt: time.Now()}
+	snapshot.Id = snapshot.StartedAt.UnixNano()
+	var snapshotMu sync.Mutex
+	snapshotScopes := []index.ScopeResources{}
+
+	scopes := resolveProfiles(ctx, c.Profiles, true)
+	targets := scanTargets(Query{Searchers: Searchers()})
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, crawlConcurrency)
+
+	for _, scope := range scopes {
+		if scope.err != nil {
+			continue
+		}
+		for _, target := range targets {
+			if _, ok := target.searcher.(Decoder); !ok {
+				continue
+			}
+			for _, region := range regionsFor(target.searcher, scope.regions) {
+				wg.Add(1)
+				go func(scope profileScope, region string, target scanTarget) {
+					defer wg.Done()
+					sem <- struct{}{}
+					defer func() { <-sem }()
+					if resources, ok := c.crawlScope(ctx, scope, region, target); ok {
+						snapshotMu.Lock()
+						snapshotScopes = append(snapshotScopes, resources)
+						snapshotMu.Unlock()
+					}
+				}(scope, region, target)
+			}
+		}
+	}
+
+	wg.Wait()
+
+	snapshot.FinishedAt = time.Now()
+	if err := c.Index.PutSnapshot(snapshot, snapshotScopes); err != nil {
+		log.Printf("error storing crawl snapshot: %v", err)
+	}
+	if c.SnapshotRetention > 0 {
+		if err := c.Index.PruneSnapshots(time.Now().Add(-c.SnapshotRetention)); err != nil {
+			log.Printf("error pruning crawl snapshots: %v", err)
+		}
+	}
+}
+
+// crawlScope lists and indexes one scan target, it returns the resources for the snapshot when the listing
+// was complete and the resource type has IDs.
+func (c *Crawler) crawlScope(ctx context.Context, scope profileScope, region string, target scanTarget) (index.ScopeResources, bool) {
+	indexScope := index.Scope{
+		Profile:         scope.profile.Name,
+		Region:          region,
+		ResourceType:    target.searcher.Type(),
+		ResourceSubType: target.resourceSubType,
+	}
+	status := index.CrawlStatus{Scope: indexScope, Status: index.StatusOk, StartedAt: time.Now()}
+
+	resources, err := target.searcher.Fetch(ctx, scope.cfg, region, target.resourceSubType, c.MaxPages)
+	status.Duration = time.Since(status.StartedAt).Milliseconds()
+	status.Count = len(resources)
+
+	truncatedErr, failedErr := splitTruncation(err)
+	switch {
+	case failedErr != nil:
+		// keep the last complete listing of the scope rather than replacing it with a partial one
+		status.Status = index.StatusFailed
+		status.Error = failedErr.Error()
+	case truncatedErr != nil:
+		status.Status = index.StatusTruncated
+		status.Error = truncatedErr.Error()
+	}
+
+	if failedErr == nil {
+		data, err := json.Marshal(resources)
+		if err == nil {
+			err = c.Index.PutListing(index.Listing{
+				Scope:     indexScope,
+				FetchedAt: status.StartedAt,
+				Truncated: truncatedErr != nil,
+				Resources: data,
+			})
+		}
+		if err != nil {
+			log.Printf("profile '%s', error indexing %s resources in region %s: %v", scope.profile.Name, indexScope.ResourceType, region, err)
+			status.Status = index.StatusFailed
+			status.Error = err.Error()
+		}
+	}
+
+	if err := c.Index.PutStatus(status); err != nil {
+		log.Printf("profile '%s', error recording the crawl status of %s in region %s: %v", scope.profile.Name, indexScope.ResourceType, region, err)
+	}
+
+	identifier, ok := target.searcher.(Identifier)
+	if !ok || status.Status != index.StatusOk {
+		return index.ScopeResources{}, false
+	}
+	return snapshotResources(scope, region, target, identifier, indexScope, resources)
+}
+
+// snapshotResources converts the listed resources into their search results, by resource ID.
+func snapshotResources(scope profileScope, region string, target scanTarget, identifier Identifier, indexScope index.Scope, resources []interface{}) (index.ScopeResources, bool) {
+	resultScope := scope.resultScope(region, target)
+
+	snapshotted := index.ScopeResources{Scope: indexScope, Resources: map[string]json.RawMessage{}}
+	for _, resource := range resources {
+		data, err := json.Marshal(target.searcher.Result(resource, resultScope))
+		if err != nil {
+			log.Printf("profile '%s', error snapshotting %s resources in region %s: %v", scope.profile.Name, indexScope.ResourceType, region, err)
+			return index.ScopeResources{}, false
+		}
+		snapshotted.Resources[identifier.ResourceId(resource)] = data
+	}
+	return snapshotted, true
+}
+
+// indexedListing returns the indexed listing of a scan target and its decoded resources, false when the
+// target can't be indexed or wasn't crawled yet.
+func indexedListing(idx *index.Index, profile profileScope, region string, target scanTarget) ([]interface{}, index.Listing, bool) {
+	decoder, ok := target.searcher.(Decoder)
+	if !ok {
+		return nil, index.Listing{}, false
+	}
+
+	listing, found, err := idx.Listing(index.Scope{
+		Profile:         profile.profile.Name,
+		Region:          region,
+		ResourceType:    target.searcher.Type(),
+		ResourceSubType: target.resourceSubType,
+	})
+	if err != nil {
+		log.Printf("profile '%s', error reading indexed %s resources in region %s: %v", profile.profile.Name, target.searcher.Type(), region, err)
+		return nil, index.Listing{}, false
+	}
+	if !found {
+		return nil, index.Listing{}, false
+	}
+
+	resources, err := decoder.Decode(target.resourceSubType, listing.Resources)
+	if err != nil {
+		log.Printf("profile '%s', error decoding indexed %s resources in region %s: %v", profile.profile.Name, target.searcher.Type(), region, err)
+		return nil, index.Listing{}, false
+	}
+	return resources, listing, true
+}
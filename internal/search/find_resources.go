@@ -4,282 +4,503 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/aviadhaham/cloudcate/internal/config"
+	"github.com/aviadhaham/cloudcate/internal/index"
 	"github.com/aviadhaham/cloudcate/internal/services"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	aws_config "github.com/aws/aws-sdk-go-v2/config"
 )
 
-func findResourcesInRegion(profile string, cfg aws.Config, region string, resourceSubType string, resourceType string, resourceName string) ([]interface{}, error) {
-	associatedAwsAccount := GetAwsAccount(cfg, region)
-	var results []interface{}
+// Query describes what to search for.
+type Query struct {
+	// Searchers are the resource types to search, all of them are searched concurrently.
+	Searchers []Searcher
+	// ResourceSubType narrows a single searched type to one of its subtypes, when empty every subtype is searched.
+	ResourceSubType string
+	ResourceName    string
+	// Terms, when set, are searched all at once instead of ResourceName: every resource is listed once
+	// and matched against each of them (see FindResourcesBulk).
+	Terms []string
+	// MaxPages caps the pages walked by every paginated AWS call, zero means no cap.
+	MaxPages int
+	// DedupeAccounts searches every account once, through the first working profile of the account,
+	// instead of once per profile.
+	DedupeAccounts bool
+	// Refresh ignores the cached listings and configurations, everything is fetched again (and cached).
+	Refresh bool
+	// Regions are the names (or glob patterns, e.g. eu-*) of the searched regions, every region when empty.
+	Regions []string
+	// Index, when set, answers the search from the crawled listings, the targets that weren't crawled yet are searched live.
+	Index *index.Index
+	// Match is how the searched terms are compared with the resources, a case-insensitive substring by default.
+	Match services.MatchOptions
+	// Raw adds the resources as returned by AWS to the results.
+	Raw bool
+}
 
-	switch resourceType {
-	case "vpc":
-		vpcList, err := services.FindVpc(cfg, region, resourceName)
-		if err != nil {
-			return nil, fmt.Errorf("error finding VPCs: %v", err)
-		}
-		for _, vpc := range vpcList {
-			vpcSearchResult := VpcSearchResult{
-				SearchResultNonGlobal: SearchResultNonGlobal{
-					SearchResult: SearchResult{
-						Account: associatedAwsAccount,
-						Profile: profile,
-					},
-					Region: region,
-				},
-				VpcId:     *vpc.VpcId,
-				CidrBlock: *vpc.CidrBlock,
-			}
+// NewQuery builds the query of a resource type ("all", or empty, for every registered type but the Explicit
+// ones), an optional subtype and the searched name, matched with the match options, with the default page cap.
+func NewQuery(resourceType string, resourceSubType string, resourceName string, match services.MatchOptions) (Query, error) {
+	if _, err := ParseTerm(resourceName, match); err != nil {
+		return Query{}, fmt.Errorf("invalid resource_name: %w", err)
+	}
 
-			if vpc.Tags != nil {
-				for _, tag := range vpc.Tags {
-					if *tag.Key == "Name" {
-						vpcSearchResult.VpcName = *tag.Value
-						break
-					}
-				}
-			}
+	query := Query{
+		ResourceSubType: resourceSubType,
+		ResourceName:    resourceName,
+		MaxPages:        config.DefaultMaxPages,
+		Match:           match,
+	}
 
-			results = append(results, vpcSearchResult)
+	if resourceType == "" || resourceType == "all" {
+		if resourceSubType != "" {
+			return Query{}, fmt.Errorf("resource_subtype requires a single resource_type")
 		}
-	case "loadbalancer":
-		lbSlice, _ := services.FindLoadBalancer(cfg, region, resourceName)
-		for _, lb := range lbSlice {
-			results = append(results, LoadBalancerSearchResult{
-				SearchResultNonGlobal: SearchResultNonGlobal{
-					SearchResult: SearchResult{
-						Account: associatedAwsAccount,
-						Profile: profile,
-					},
-					Region: region,
-				},
-				LoadBalancerName:    lb[0],
-				LoadBalancerDnsName: lb[1],
-			})
+		query.Searchers = allSearchers()
+		return query, nil
+	}
+
+	searcher, ok := Lookup(resourceType)
+	if !ok {
+		return Query{}, fmt.Errorf("unknown resource_type: %s", resourceType)
+	}
+	if resourceSubType != "" && !hasSubType(searcher, resourceSubType) {
+		return Query{}, fmt.Errorf("unknown resource_subtype for %s: %s", resourceType, resourceSubType)
+	}
+	query.Searchers = []Searcher{searcher}
+	return query, nil
+}
+
+func hasSubType(searcher Searcher, resourceSubType string) bool {
+	for _, subType := range searcher.SubTypes() {
+		if subType == resourceSubType {
+			return true
 		}
-	case "ec2":
-		instances, err := services.FindEc2(cfg, region, resourceName)
+	}
+	return false
+}
+
+// Response is the outcome of a search: the matching resources and everything that could not be searched.
+type Response struct {
+	Results   []Resource    `json:"results"`
+	Errors    []SearchError `json:"errors"`
+	Truncated []Truncation  `json:"truncated"`
+	// DataAge is the age of the oldest listing the results were matched against, zero when all were fetched live.
+	DataAge int64 `json:"data_age_ms"`
+}
+
+// terms returns the searched terms.
+func (q Query) terms() []string {
+	if len(q.Terms) > 0 {
+		return q.Terms
+	}
+	return []string{q.ResourceName}
+}
+
+// TermResults are the results of one term of a bulk search.
+type TermResults struct {
+	Term    string     `json:"term"`
+	Results []Resource `json:"results"`
+}
+
+// BulkResponse is the outcome of a bulk search, with the results grouped by term. Terms that matched
+// nothing are listed too, with no results.
+type BulkResponse struct {
+	Terms     []TermResults `json:"terms"`
+	Errors    []SearchError `json:"errors"`
+	Truncated []Truncation  `json:"truncated"`
+	DataAge   int64         `json:"data_age_ms"`
+}
+
+// Event kinds emitted by StreamResources
+const (
+	EventResults   = "results"
+	EventError     = "search_error"
+	EventTruncated = "truncated"
+	EventProgress  = "progress"
+	EventSummary   = "summary"
+)
+
+// Event reports the progress of a running search. Every event carries how many
+// region scans are done out of the total, plus the payload of its kind.
+type Event struct {
+	Event     string       `json:"event"`
+	Done      int          `json:"done"`
+	Total     int          `json:"total"`
+	Profile   string       `json:"profile,omitempty"`
+	Region    string       `json:"region,omitempty"`
+	Term      string       `json:"term,omitempty"`
+	Results   []Resource   `json:"results,omitempty"`
+	Error     *SearchError `json:"error,omitempty"`
+	Truncated *Truncation  `json:"truncated,omitempty"`
+	Summary   *Summary     `json:"summary,omitempty"`
+}
+
+// Summary closes a streamed search.
+type Summary struct {
+	Results   int   `json:"results"`
+	Errors    int   `json:"errors"`
+	Truncated int   `json:"truncated"`
+	Duration  int64 `json:"duration_ms"`
+	DataAge   int64 `json:"data_age_ms"`
+}
+
+type profileScope struct {
+	profile Profile
+	// profiles lists every profile of the account when profiles were deduplicated by account
+	profiles []string
+	account  AccountIdentity
+	cfg      aws.Config
+	regions  []string
+	err      *SearchError
+}
+
+// resultScope is the scope of the resources of a scan target found in a region of the profile.
+func (p profileScope) resultScope(region string, target scanTarget) Scope {
+	return Scope{
+		Profile:         p.profile.Name,
+		Account:         p.account,
+		OuPath:          p.profile.OuPath,
+		Profiles:        p.profiles,
+		Region:          region,
+		ResourceType:    target.searcher.Type(),
+		ResourceSubType: target.resourceSubType,
+	}
+}
+
+// scanTarget is a resource type, and one of its subtypes, to search in every region of a profile.
+type scanTarget struct {
+	searcher        Searcher
+	resourceSubType string
+}
+
+type regionResult struct {
+	profile string
+	region  string
+	// results are grouped by the term they matched
+	results   map[string][]Resource
+	err       *SearchError
+	truncated *Truncation
+	fetchedAt time.Time
+}
+
+// compileTerms parses every searched term once per search. Terms are validated by NewQuery and the callers
+// of bulk searches, a term that still fails to parse is matched as a free text substring.
+func compileTerms(terms []string, match services.MatchOptions) map[string]Expr {
+	exprs := map[string]Expr{}
+	for _, term := range terms {
+		expr, err := ParseTerm(term, match)
 		if err != nil {
-			return nil, fmt.Errorf("error finding EC2 instances: %v", err)
-		}
-		if len(instances) == 0 {
-			return nil, nil
+			expr = wordExpr{matcher: services.SubstringMatcher(term)}
 		}
-		for _, instance := range instances {
-			ec2SearchResult := Ec2SearchResult{
-				SearchResultNonGlobal: SearchResultNonGlobal{
-					SearchResult: SearchResult{
-						Account: associatedAwsAccount,
-						Profile: profile,
-					},
-					Region: region,
-				},
-				InstanceId: *instance.InstanceId,
-			}
+		exprs[term] = expr
+	}
+	return exprs
+}
 
-			if instance.Tags != nil {
-				for _, tag := range instance.Tags {
-					if *tag.Key == "Name" {
-						ec2SearchResult.InstanceName = *tag.Value
-						break
-					}
-				}
-			}
+func findResourcesInRegion(ctx context.Context, profile profileScope, region string, target scanTarget, query Query, exprs map[string]Expr) regionResult {
+	searcher := target.searcher
+	scope := profile.resultScope(region, target)
+	res := regionResult{profile: profile.profile.Name, region: region, results: map[string][]Resource{}}
 
-			if instance.PrivateIpAddress != nil {
-				ec2SearchResult.PrivateIpAddress = *instance.PrivateIpAddress
-			}
-			if instance.PrivateDnsName != nil {
-				ec2SearchResult.PrivateDnsName = *instance.PrivateDnsName
-			}
-			if instance.PublicDnsName != nil {
-				ec2SearchResult.PublicDnsName = *instance.PublicDnsName
-			}
-			if instance.PublicIpAddress != nil {
-				ec2SearchResult.PublicIpAddress = *instance.PublicIpAddress
-			}
-
-			results = append(results, ec2SearchResult)
+	resources, fetchedAt, err := fetchListing(ctx, profile, region, target, query)
+	res.fetchedAt = fetchedAt
+	if err != nil {
+		truncatedErr, failedErr := splitTruncation(err)
+		if truncatedErr != nil {
+			truncation := newTruncation(scope, searcher.Type(), truncatedErr)
+			res.truncated = &truncation
 		}
-	case "s3":
-		bucketsSlice := services.FindS3Bucket(cfg, region, resourceName)
-		if bucketsSlice == nil {
-			return nil, fmt.Errorf("no S3 buckets found")
+		if failedErr != nil {
+			log.Printf("profile '%s', error searching for %s resources in region %s: %v", profile.profile.Name, searcher.Type(), region, failedErr)
+			searchErr := newSearchError(scope, searcher.Type(), failedErr)
+			res.err = &searchErr
 		}
+	}
 
-		for _, bucket := range bucketsSlice {
-			results = append(results, S3SearchResult{
-				SearchResult: SearchResult{
-					Account: associatedAwsAccount,
-					Profile: profile,
-				},
-				BucketName: bucket,
-			})
-		}
-	case "dns":
-		dnsRecordsMap := services.FindDns(cfg, region, resourceName)
-		if len(dnsRecordsMap) == 0 {
-			return nil, fmt.Errorf("no DNS records found")
+	// bare words are matched by the searcher, the fields of a resource are only needed ahead of a match by the
+	// other expressions
+	fieldsNeeded := false
+	for _, term := range query.terms() {
+		if _, isWord := exprs[term].(wordExpr); !isWord {
+			fieldsNeeded = true
 		}
-		for zoneName, dnsRecords := range dnsRecordsMap {
-			for _, dnsRecord := range dnsRecords {
-				results = append(results, DNSSearchResult{
-					SearchResult: SearchResult{
-						Account: associatedAwsAccount,
-						Profile: profile,
-					},
-					HostedZoneName: zoneName,
-					DnsRecordName:  aws.ToString(dnsRecord.Name),
-				})
-			}
+	}
+
+	for _, resource := range resources {
+		var result *Resource
+		var fields Fields
+		if fieldsNeeded {
+			fields = searcher.Fields(resource)
 		}
-	case "iam":
-		if resourceSubType == "user" {
-			users, err := services.FindIamUser(cfg, region, resourceName)
-			if err != nil {
-				log.Printf("profile '%s': %v\n", profile, err)
+		for _, term := range query.terms() {
+			if !exprs[term].Eval(searcher, resource, fields) {
+				continue
 			}
-			if users == nil {
-				return nil, fmt.Errorf("no IAM users found")
-			}
-			for _, user := range users {
-				if user != "" {
-					results = append(results, IamUserSearchResult{
-						SearchResult: SearchResult{
-							Account: associatedAwsAccount,
-							Profile: profile,
-						},
-						UserName: user,
-					})
+			if result == nil {
+				converted := searcher.Result(resource, scope)
+				if query.Raw {
+					converted.Raw = resource
 				}
+				result = &converted
 			}
-		}
-		if resourceSubType == "key" {
-			accessKeys, err := services.FindIamUserKey(cfg, region, resourceName)
-			if err != nil {
-				log.Printf("profile '%s': %v\n", profile, err)
-			}
-			if accessKeys == nil {
-				return nil, fmt.Errorf("no IAM user access keys users found")
-			}
-			for user, key := range accessKeys {
-				results = append(results, IamUserKeySearchResult{
-					IamUserSearchResult: IamUserSearchResult{
-						SearchResult: SearchResult{
-							Account: associatedAwsAccount,
-							Profile: profile,
-						},
-						UserName: user,
-					},
-					AccessKey: key,
-				})
+			if fields == nil {
+				fields = searcher.Fields(resource)
 			}
+			// the same resource may match several terms of a bulk search, through different fields
+			termResult := *result
+			termResult.MatchedFields = matchedFields(exprs[term], searcher, resource, fields)
+			res.results[term] = append(res.results[term], termResult)
 		}
+	}
+	return res
+}
 
-	case "elastic_ip":
-		addresses, err := services.FindElasticIp(cfg, region, resourceName)
-		if err != nil {
-			return nil, fmt.Errorf("error finding elastic IP addresses: %v", err)
-		}
+// resolveProfiles loads the configuration, the account and the regions of every profile concurrently,
+// so they are looked up once per search no matter how many resource types are searched.
+func resolveProfiles(ctx context.Context, profiles []Profile, refresh bool) []profileScope {
+	scopes := make([]profileScope, len(profiles))
+	var wg sync.WaitGroup
+
+	for i, profile := range profiles {
+		wg.Add(1)
+		go func(i int, profile Profile) {
+			defer wg.Done()
+			scopes[i].profile = profile
+
+			fail := func(err error) {
+				log.Printf("profile '%s': %v", profile.Name, err)
+				searchErr := newSearchError(Scope{Profile: profile.Name}, "", err)
+				scopes[i].err = &searchErr
+			}
 
-		for _, address := range addresses {
-			elasticIpSearchResult := ElasticIpSearchResult{
-				SearchResultNonGlobal: SearchResultNonGlobal{
-					SearchResult: SearchResult{
-						Account: associatedAwsAccount,
-						Profile: profile,
-					},
-					Region: region,
-				},
-				PublicIp: *address.PublicIp,
+			// stop fanning out once the caller went away or the search deadline passed
+			if ctx.Err() != nil {
+				fail(fmt.Errorf("profile was not searched: %w", ctx.Err()))
+				return
 			}
 
-			if address.InstanceId != nil && *address.InstanceId != "" {
-				elasticIpSearchResult.InstanceId = *address.InstanceId
+			cfg, regions, err := loadProfile(ctx, profile, refresh)
+			if err != nil {
+				fail(err)
+				return
 			}
 
-			results = append(results, elasticIpSearchResult)
+			scopes[i].cfg = cfg
+			scopes[i].regions = regions
+			scopes[i].account = GetAccountIdentity(ctx, profile, cfg, regions[0])
+		}(i, profile)
+	}
+
+	wg.Wait()
+	return scopes
+}
+
+// dedupeAccounts collapses the profiles that resolved to the same account into the first of them,
+// which then lists all of them. Profiles that failed to resolve, or whose account is unknown, are kept as is.
+func dedupeAccounts(scopes []profileScope) []profileScope {
+	deduped := []profileScope{}
+	byAccount := map[string]int{}
+
+	for _, scope := range scopes {
+		accountId := scope.account.AccountId
+		if scope.err != nil || accountId == "" {
+			deduped = append(deduped, scope)
+			continue
 		}
-	case "cloudfront":
-		distributions, err := services.FindCloudfront(cfg, region, resourceName)
-		if err != nil && len(distributions) == 0 {
-			return nil, fmt.Errorf("error finding cloudfront distributions: %v", err)
+		if i, ok := byAccount[accountId]; ok {
+			deduped[i].profiles = append(deduped[i].profiles, scope.profile.Name)
+			continue
 		}
+		scope.profiles = []string{scope.profile.Name}
+		byAccount[accountId] = len(deduped)
+		deduped = append(deduped, scope)
+	}
+	return deduped
+}
 
-		for _, distribution := range distributions {
-			cloudFrontSearchResult := CloudfrontSearchResult{
-				SearchResult: SearchResult{
-					Account: associatedAwsAccount,
-					Profile: profile,
-				},
-				DistributionArn: *distribution.ARN,
-				DistributionId:  *distribution.Id,
-				DomainName:      *distribution.DomainName,
+// scanTargets expands the searched resource types into their subtypes.
+func scanTargets(query Query) []scanTarget {
+	targets := []scanTarget{}
+	for _, searcher := range query.Searchers {
+		switch {
+		case query.ResourceSubType != "":
+			targets = append(targets, scanTarget{searcher: searcher, resourceSubType: query.ResourceSubType})
+		case len(searcher.SubTypes()) == 0:
+			targets = append(targets, scanTarget{searcher: searcher})
+		default:
+			for _, resourceSubType := range searcher.SubTypes() {
+				targets = append(targets, scanTarget{searcher: searcher, resourceSubType: resourceSubType})
 			}
-
-			results = append(results, cloudFrontSearchResult)
 		}
 	}
-	return results, nil
+	return targets
 }
 
-func FindResources(profiles []string, servicesGlobality map[string]bool, resourceType string, resourceSubType string, resourceName string) ([]interface{}, error) {
-	var results []interface{}
-	var wg sync.WaitGroup
-	resultChan := make(chan []interface{})
+// regionsFor returns the regions a resource type is searched in, global types need only one.
+func regionsFor(searcher Searcher, regions []string) []string {
+	if searcher.Global() && len(regions) > 0 {
+		return regions[:1]
+	}
+	return regions
+}
 
-	for _, profile := range profiles {
-		cfg, err := aws_config.LoadDefaultConfig(context.TODO(), aws_config.WithSharedConfigProfile(profile))
-		if err != nil {
-			return nil, fmt.Errorf("failed to load configuration for profile, %v", err)
-		}
+// searchConcurrency caps the scans a search runs at once, a search of every type across many profiles and
+// regions would otherwise open thousands of connections.
+const searchConcurrency = 32
 
-		regions, err := GetRegions(profile)
-		if err != nil {
-			log.Fatalf("Failed to get regions: %v", err)
-		}
+// StreamResources searches every profile, region and resource type of the query and calls emit as soon as
+// each scan's results arrive. emit is never called concurrently, the last event is always the summary.
+func StreamResources(ctx context.Context, profiles []Profile, query Query, emit func(Event)) {
+	start := time.Now()
+	summary := Summary{}
+	done, total := 0, 0
 
-		if config.ServicesGlobality[resourceType] {
-			wg.Add(1)
-			go func(profile string, cfg aws.Config, region string) {
-				defer wg.Done()
-				res, err := findResourcesInRegion(profile, cfg, region, resourceSubType, resourceType, resourceName)
-				if err != nil {
-					log.Printf("profile '%s', error searching for resources in region %s: %v", profile, region, err)
-					return
-				}
-				resultChan <- res
-			}(profile, cfg, regions[0])
-			continue
+	scopes := resolveProfiles(ctx, profiles, query.Refresh)
+	for i := range scopes {
+		scopes[i].regions = FilterRegions(scopes[i].regions, query.Regions)
+	}
+	if query.DedupeAccounts {
+		scopes = dedupeAccounts(scopes)
+	}
+	targets := scanTargets(query)
+	exprs := compileTerms(query.terms(), query.Match)
+	for _, scope := range scopes {
+		for _, target := range targets {
+			total += len(regionsFor(target.searcher, scope.regions))
 		}
+	}
 
-		for _, region := range regions {
-			wg.Add(1)
-			go func(profile string, cfg aws.Config, region string) {
-				defer wg.Done()
-				res, err := findResourcesInRegion(profile, cfg, region, resourceSubType, resourceType, resourceName)
-				if err != nil {
-					log.Printf("profile '%s', error searching for resources in region %s: %v", profile, region, err)
-					return
-				}
-				resultChan <- res
-			}(profile, cfg, region)
+	for _, scope := range scopes {
+		if scope.err != nil {
+			summary.Errors++
+			emit(Event{Event: EventError, Done: done, Total: total, Profile: scope.profile.Name, Error: scope.err})
 		}
 	}
 
+	resultChan := make(chan regionResult)
+
+	// scans are started from their own goroutine, as a slot frees up only once the results of a scan are read
 	go func() {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, searchConcurrency)
+
+		for _, scope := range scopes {
+			for _, target := range targets {
+				for _, region := range regionsFor(target.searcher, scope.regions) {
+					sem <- struct{}{}
+					wg.Add(1)
+					go func(scope profileScope, region string, target scanTarget) {
+						defer wg.Done()
+						defer func() { <-sem }()
+						resultChan <- findResourcesInRegion(ctx, scope, region, target, query, exprs)
+					}(scope, region, target)
+				}
+			}
+		}
+
 		wg.Wait()
 		close(resultChan)
 	}()
 
+	oldest := start
 	for res := range resultChan {
-		results = append(results, res...)
+		done++
+		if !res.fetchedAt.IsZero() && res.fetchedAt.Before(oldest) {
+			oldest = res.fetchedAt
+		}
+		if res.err != nil {
+			summary.Errors++
+			emit(Event{Event: EventError, Done: done, Total: total, Profile: res.profile, Region: res.region, Error: res.err})
+		}
+		if res.truncated != nil {
+			summary.Truncated++
+			emit(Event{Event: EventTruncated, Done: done, Total: total, Profile: res.profile, Region: res.region, Truncated: res.truncated})
+		}
+		for _, term := range query.terms() {
+			if len(res.results[term]) == 0 {
+				continue
+			}
+			summary.Results += len(res.results[term])
+			event := Event{Event: EventResults, Done: done, Total: total, Profile: res.profile, Region: res.region, Results: res.results[term]}
+			if len(query.Terms) > 0 {
+				event.Term = term
+			}
+			emit(event)
+		}
+		emit(Event{Event: EventProgress, Done: done, Total: total})
 	}
 
-	return results, nil
+	summary.Duration = time.Since(start).Milliseconds()
+	summary.DataAge = start.Sub(oldest).Milliseconds()
+	emit(Event{Event: EventSummary, Done: done, Total: total, Summary: &summary})
+}
+
+func FindResources(ctx context.Context, profiles []Profile, query Query) Response {
+	response := Response{Results: []Resource{}, Errors: []SearchError{}, Truncated: []Truncation{}}
+
+	StreamResources(ctx, profiles, query, func(event Event) {
+		switch event.Event {
+		case EventResults:
+			response.Results = append(response.Results, event.Results...)
+		case EventError:
+			response.Errors = append(response.Errors, *event.Error)
+		case EventTruncated:
+			response.Truncated = append(response.Truncated, *event.Truncated)
+		case EventSummary:
+			response.DataAge = event.Summary.DataAge
+		}
+	})
+
+	return response
+}
+
+// UniqueTerms trims the terms and drops the empty and repeated ones, keeping their order.
+func UniqueTerms(terms []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" || seen[term] {
+			continue
+		}
+		seen[term] = true
+		unique = append(unique, term)
+	}
+	return unique
+}
+
+// FindResourcesBulk searches all the terms of the query at once: every resource type is listed once per
+// profile and region, and matched against each term.
+func FindResourcesBulk(ctx context.Context, profiles []Profile, query Query) BulkResponse {
+	response := BulkResponse{Terms: []TermResults{}, Errors: []SearchError{}, Truncated: []Truncation{}}
+	if len(query.Terms) == 0 {
+		return response
+	}
+
+	byTerm := map[string]int{}
+	for _, term := range query.Terms {
+		byTerm[term] = len(response.Terms)
+		response.Terms = append(response.Terms, TermResults{Term: term, Results: []Resource{}})
+	}
+
+	StreamResources(ctx, profiles, query, func(event Event) {
+		switch event.Event {
+		case EventResults:
+			i := byTerm[event.Term]
+			response.Terms[i].Results = append(response.Terms[i].Results, event.Results...)
+		case EventError:
+			response.Errors = append(response.Errors, *event.Error)
+		case EventTruncated:
+			response.Truncated = append(response.Truncated, *event.Truncated)
+		case EventSummary:
+			response.DataAge = event.Summary.DataAge
+		}
+	})
+
+	return response
 }
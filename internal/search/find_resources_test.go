@@ -0,0 +1,86 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aviadhaham/cloudcate/internal/config"
+	"github.com/aviadhaham/cloudcate/internal/services"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// cachedSearcher lists resources only from the cache, a fetch fails the test.
+type cachedSearcher struct {
+	t *testing.T
+}
+
+func (cachedSearcher) Type() string       { return "cached_test" }
+func (cachedSearcher) SubTypes() []string { return nil }
+func (cachedSearcher) Global() bool       { return false }
+
+func (s cachedSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	s.t.Errorf("listing of region %s was fetched instead of read from the cache", region)
+	return nil, nil
+}
+
+func (cachedSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return matcher.Match(resource.(string))
+}
+
+func (cachedSearcher) Fields(resource interface{}) Fields {
+	fields := Fields{}
+	fields.Add(FieldName, resource.(string))
+	return fields
+}
+
+func (cachedSearcher) Result(resource interface{}, scope Scope) Resource {
+	result := scope.RegionalResource()
+	result.Id = resource.(string)
+	return result
+}
+
+func TestFindResourcesDataAgeOnCacheHit(t *testing.T) {
+	const profileName = "data-age-test"
+	const region = "eu-west-1"
+	age := time.Minute
+	fetchedAt := time.Now().Add(-age)
+
+	cacheMu.Lock()
+	profileConfigs[profileName] = cachedProfile{regions: []string{region}, loadedAt: time.Now()}
+	listingCache[listingKey{profile: profileName, region: region, resourceType: "cached_test"}] = cachedListing{
+		resources: []interface{}{"web-1", "db-1"},
+		fetchedAt: fetchedAt,
+	}
+	cacheMu.Unlock()
+	identityMu.Lock()
+	identityCache[profileName] = cachedIdentity{identity: AccountIdentity{AccountId: "123456789012"}, expires: time.Now().Add(time.Hour)}
+	identityMu.Unlock()
+	t.Cleanup(func() {
+		cacheMu.Lock()
+		delete(profileConfigs, profileName)
+		delete(listingCache, listingKey{profile: profileName, region: region, resourceType: "cached_test"})
+		cacheMu.Unlock()
+		identityMu.Lock()
+		delete(identityCache, profileName)
+		identityMu.Unlock()
+	})
+
+	query := Query{
+		Searchers:    []Searcher{cachedSearcher{t: t}},
+		ResourceName: "web",
+		MaxPages:     config.DefaultMaxPages,
+	}
+	response := FindResources(context.Background(), []Profile{{Name: profileName}}, query)
+
+	if len(response.Results) != 1 || response.Results[0].Id != "web-1" {
+		t.Fatalf("results = %+v, want the cached web-1", response.Results)
+	}
+	if len(response.Errors) != 0 {
+		t.Fatalf("errors = %+v, want none", response.Errors)
+	}
+	if response.DataAge < age.Milliseconds() {
+		t.Errorf("data_age_ms = %d, want at least the age of the cached listing (%d)", response.DataAge, age.Milliseconds())
+	}
+}
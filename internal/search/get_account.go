@@ -3,24 +3,118 @@ package search
 import (
 	"context"
 	"errors"
-	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aviadhaham/cloudcate/internal/config"
+	"github.com/aviadhaham/cloudcate/internal/services"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
-func GetAwsAccount(cfg aws.Config, region string) string {
+// AccountIdentity is the account a profile resolves to.
+type AccountIdentity struct {
+	AccountId    string
+	AccountAlias string
+	// AccountName is the name of the account in its organization, empty when neither ORG_PROFILE discovered
+	// the account nor the profile itself can describe it (see services.GetOrganizationAccountName)
+	AccountName string
+}
+
+type cachedIdentity struct {
+	identity AccountIdentity
+	expires  time.Time
+}
+
+var (
+	identityMu    sync.Mutex
+	identityCache = map[string]cachedIdentity{}
+
+	// accountNames maps the accounts of the discovered organization to their names
+	accountNamesMu sync.RWMutex
+	accountNames   = map[string]string{}
+)
+
+func GetAwsAccount(ctx context.Context, cfg aws.Config, region string) string {
 	cfg.Region = region
 
 	stsClient := sts.NewFromConfig(cfg)
-	identity, err := stsClient.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	identity, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
 		var accessDeniedErr *http.ResponseError
 		if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
 			return ""
 		}
-		fmt.Printf("\nUnable to get caller identity: %v\nRegion: %s\n", err, region)
+		log.Printf("Unable to get caller identity in region %s: %v", region, err)
+		return ""
 	}
 	return *identity.Account
 }
+
+// GetAccountIdentity resolves the account ID, IAM alias and organization account name (when it can be known)
+// of a profile.
+// Identities are cached per profile for config.IdentityTTL, an account that could not be resolved
+// is looked up again by the next search.
+func GetAccountIdentity(ctx context.Context, profile Profile, cfg aws.Config, region string) AccountIdentity {
+	identityMu.Lock()
+	cached, ok := identityCache[profile.Name]
+	identityMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.identity
+	}
+
+	identity := AccountIdentity{AccountId: profile.AccountId, AccountName: profile.AccountName}
+	if identity.AccountId == "" {
+		identity.AccountId = GetAwsAccount(ctx, cfg, region)
+		if identity.AccountId == "" {
+			return identity
+		}
+	}
+	cfg.Region = region
+	if identity.AccountName == "" {
+		identity.AccountName = organizationAccountName(identity.AccountId)
+	}
+	if identity.AccountName == "" {
+		// only the management account and delegated administrators can describe their account, the name of
+		// the other accounts stays unknown unless the organization was discovered (see rememberAccountNames)
+		name, err := services.GetOrganizationAccountName(ctx, cfg, identity.AccountId)
+		if err != nil {
+			log.Printf("profile '%s': %v", profile.Name, err)
+		}
+		identity.AccountName = name
+	}
+
+	alias, err := services.GetAccountAlias(ctx, cfg)
+	if err != nil {
+		// the alias is a nicety, the account is still searched without it
+		log.Printf("profile '%s': %v", profile.Name, err)
+	}
+	identity.AccountAlias = alias
+
+	identityMu.Lock()
+	identityCache[profile.Name] = cachedIdentity{identity: identity, expires: time.Now().Add(config.IdentityTTL)}
+	identityMu.Unlock()
+
+	return identity
+}
+
+// rememberAccountNames records the names of the organization accounts, so profiles of these accounts
+// are labelled with them too.
+func rememberAccountNames(accounts []services.OrganizationAccount) {
+	accountNamesMu.Lock()
+	defer accountNamesMu.Unlock()
+
+	for _, account := range accounts {
+		accountNames[account.Id] = account.Name
+	}
+}
+
+func organizationAccountName(accountId string) string {
+	accountNamesMu.RLock()
+	defer accountNamesMu.RUnlock()
+
+	return accountNames[accountId]
+}
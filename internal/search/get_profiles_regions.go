@@ -3,48 +3,200 @@ package search
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/aviadhaham/cloudcate/internal/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	aws_config "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
-	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
-func GetProfiles() ([]string, error) {
-	// read .aws/credetials file
-	f, err := os.Open(fmt.Sprintf("%s/.aws/credentials", os.Getenv("HOME")))
+// Profile credential kinds
+const (
+	ProfileKindStatic            = "static"
+	ProfileKindSso               = "sso"
+	ProfileKindAssumeRole        = "assume-role"
+	ProfileKindCredentialProcess = "credential_process"
+	ProfileKindOrganization      = "organization"
+	ProfileKindUnknown           = "unknown"
+)
+
+// Profile is a named profile of the shared AWS config and credentials files.
+type Profile struct {
+	Name string `json:"name"`
+	// Kind is how the profile gets its credentials, one of the ProfileKind constants
+	Kind string `json:"kind"`
+	// Region is the profile's default region, if it has one
+	Region string `json:"region,omitempty"`
+
+	// virtual profiles of organization member accounts (see GetOrganizationProfiles)
+	AccountId     string `json:"account_id,omitempty"`
+	AccountName   string `json:"account_name,omitempty"`
+	OuPath        string `json:"ou_path,omitempty"`
+	sourceProfile string
+	roleArn       string
+	externalId    string
+}
+
+// LoadConfig loads the AWS configuration of the profile, the SDK resolves its credentials
+// (static keys, SSO, assumed roles or a credential process) from the shared files.
+// Organization member accounts assume their role through the profile they were discovered with.
+func (p Profile) LoadConfig(ctx context.Context) (aws.Config, error) {
+	if p.Kind != ProfileKindOrganization {
+		return aws_config.LoadDefaultConfig(ctx, aws_config.WithSharedConfigProfile(p.Name))
+	}
+
+	cfg, err := aws_config.LoadDefaultConfig(ctx, aws_config.WithSharedConfigProfile(p.sourceProfile))
 	if err != nil {
-		fmt.Println(err)
+		return aws.Config{}, err
+	}
+	cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), p.roleArn, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = "cloudcate"
+		if p.externalId != "" {
+			o.ExternalID = aws.String(p.externalId)
+		}
+	}))
+	return cfg, nil
+}
+
+// sharedFilePath returns the path of a shared AWS file, overridden by the given environment variable.
+func sharedFilePath(envVar string, name string) string {
+	if path := os.Getenv(envVar); path != "" {
+		return path
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		home, _ = os.UserHomeDir()
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// readIniSections reads the sections of an INI file as a map of section name to its keys, in file order.
+// A missing file has no sections.
+func readIniSections(path string) ([]string, map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
 	}
 	defer f.Close()
 
-	profileList := []string{}
+	names := []string{}
+	sections := map[string]map[string]string{}
+	var current map[string]string
+
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, "[") && strings.Contains(line, "]") {
-			profile := strings.Trim(scanner.Text(), "[]")
-			profileList = append(profileList, profile)
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
 		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(strings.Trim(line, "[]"))
+			if _, exists := sections[name]; !exists {
+				names = append(names, name)
+				sections[name] = map[string]string{}
+			}
+			current = sections[name]
+			continue
+		}
+
+		// keys outside of a section and nested values (e.g. s3 settings) are skipped
+		key, value, found := strings.Cut(line, "=")
+		if current == nil || !found {
+			continue
+		}
+		current[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
 	}
 
-	return profileList, err
+	return names, sections, scanner.Err()
 }
 
-func GetRegions(profile string) ([]string, error) {
+// profileKind tells how a profile gets its credentials from its merged config and credentials keys.
+func profileKind(keys map[string]string) string {
+	switch {
+	case keys["role_arn"] != "":
+		return ProfileKindAssumeRole
+	case keys["sso_start_url"] != "" || keys["sso_session"] != "":
+		return ProfileKindSso
+	case keys["credential_process"] != "":
+		return ProfileKindCredentialProcess
+	case keys["aws_access_key_id"] != "":
+		return ProfileKindStatic
+	}
+	return ProfileKindUnknown
+}
 
-	// use .aws/credentials file to get profiles, but use only the first one in the file
-	// hardcoded region to us-east-1, because there's no chance it's not going to be active
-	cfg, err := aws_config.LoadDefaultConfig(context.TODO(), aws_config.WithSharedConfigProfile(profile))
+// GetProfiles merges the profiles of the shared credentials file and the shared config file,
+// honouring AWS_SHARED_CREDENTIALS_FILE and AWS_CONFIG_FILE.
+func GetProfiles() ([]Profile, error) {
+	names := []string{}
+	merged := map[string]map[string]string{}
+	add := func(name string, keys map[string]string) {
+		if _, exists := merged[name]; !exists {
+			names = append(names, name)
+			merged[name] = map[string]string{}
+		}
+		for key, value := range keys {
+			merged[name][key] = value
+		}
+	}
+
+	credentialsSections, credentials, err := readIniSections(sharedFilePath("AWS_SHARED_CREDENTIALS_FILE", "credentials"))
 	if err != nil {
-		log.Fatalf("failed to load configuration for profile, %v", err)
+		return nil, fmt.Errorf("failed to read shared credentials file: %w", err)
+	}
+	for _, name := range credentialsSections {
+		add(name, credentials[name])
+	}
+
+	configSections, configs, err := readIniSections(sharedFilePath("AWS_CONFIG_FILE", "config"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared config file: %w", err)
+	}
+	for _, section := range configSections {
+		// the config file names its profiles "profile <name>", except for the default one,
+		// other sections (sso-session, services) are not profiles
+		switch {
+		case section == "default":
+			add(section, configs[section])
+		case strings.HasPrefix(section, "profile "):
+			add(strings.TrimSpace(strings.TrimPrefix(section, "profile ")), configs[section])
+		}
 	}
+
+	profileList := []Profile{}
+	for _, name := range names {
+		profileList = append(profileList, Profile{
+			Name:   name,
+			Kind:   profileKind(merged[name]),
+			Region: merged[name]["region"],
+		})
+	}
+
+	return profileList, nil
+}
+
+func GetRegions(ctx context.Context, profile Profile, cfg aws.Config) ([]string, error) {
+
+	// describe the regions from the profile's own region when it has one, otherwise from us-east-1,
+	// because there's no chance it's not going to be active
 	cfg.Region = "us-east-1"
+	if profile.Region != "" {
+		cfg.Region = profile.Region
+	}
 	client := ec2.NewFromConfig(cfg)
 
 	input := &ec2.DescribeRegionsInput{
@@ -56,9 +208,9 @@ func GetRegions(profile string) ([]string, error) {
 		},
 	}
 
-	resp, err := client.DescribeRegions(context.TODO(), input)
+	resp, err := client.DescribeRegions(ctx, input)
 	if err != nil {
-		log.Printf("profile '%s', failed to describe regions, %v", profile, err)
+		log.Printf("profile '%s', failed to describe regions, %v", profile.Name, err)
 		// Return a hardcoded list of regions instead of terminating the application
 		return config.AwsFullRegionsList, nil
 	}
@@ -70,3 +222,41 @@ func GetRegions(profile string) ([]string, error) {
 
 	return regionsList, nil
 }
+
+// FilterProfiles returns the profiles whose name matches one of the patterns, which are profile names
+// or globs such as prod-*. Without patterns every profile is returned.
+func FilterProfiles(profiles []Profile, patterns []string) []Profile {
+	if len(patterns) == 0 {
+		return profiles
+	}
+
+	filtered := []Profile{}
+	for _, profile := range profiles {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, profile.Name); matched {
+				filtered = append(filtered, profile)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// FilterRegions returns the regions matching one of the patterns, which are region names or globs such as eu-*.
+// Without patterns every region is returned.
+func FilterRegions(regions []string, patterns []string) []string {
+	if len(patterns) == 0 {
+		return regions
+	}
+
+	filtered := []string{}
+	for _, region := range regions {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, region); matched {
+				filtered = append(filtered, region)
+				break
+			}
+		}
+	}
+	return filtered
+}
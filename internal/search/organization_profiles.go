@@ -0,0 +1,89 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aviadhaham/cloudcate/internal/services"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// defaultOrganizationRole is the role AWS Organizations creates in the accounts it creates.
+const defaultOrganizationRole = "OrganizationAccountAccessRole"
+
+// OrganizationOptions configures the discovery of the member accounts of an AWS Organization.
+type OrganizationOptions struct {
+	// Profile is the management or delegated administrator profile the accounts are listed with
+	Profile string
+	// RoleName is the role assumed in every member account, OrganizationAccountAccessRole when empty
+	RoleName string
+	// ExternalId is passed when assuming the role, if the role requires one
+	ExternalId string
+}
+
+// GetOrganizationProfiles lists the active member accounts of the organization and returns a virtual
+// profile for each one of them, which assumes the configured role through the organization profile.
+// The account of the organization profile itself is skipped, it is searched through its own profile.
+func GetOrganizationProfiles(ctx context.Context, profiles []Profile, opts OrganizationOptions) ([]Profile, error) {
+	if opts.RoleName == "" {
+		opts.RoleName = defaultOrganizationRole
+	}
+
+	var orgProfile *Profile
+	for i := range profiles {
+		if profiles[i].Name == opts.Profile {
+			orgProfile = &profiles[i]
+			break
+		}
+	}
+	if orgProfile == nil {
+		return nil, fmt.Errorf("organization profile '%s' was not found", opts.Profile)
+	}
+
+	cfg, err := orgProfile.LoadConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration for organization profile '%s': %w", opts.Profile, err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	accounts, err := services.ListOrganizationAccounts(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	rememberAccountNames(accounts)
+	ownAccount := GetAwsAccount(ctx, cfg, cfg.Region)
+
+	orgProfiles := []Profile{}
+	for _, account := range accounts {
+		if account.Id == ownAccount {
+			continue
+		}
+		if !services.IsActiveAccount(account) {
+			log.Printf("organization account %s (%s) is %s, skipping it", account.Name, account.Id, account.Status)
+			continue
+		}
+
+		partition := "aws"
+		if accountArn, err := arn.Parse(account.Arn); err == nil {
+			partition = accountArn.Partition
+		}
+
+		orgProfiles = append(orgProfiles, Profile{
+			Name:          fmt.Sprintf("org:%s/%s", account.Name, account.Id),
+			Kind:          ProfileKindOrganization,
+			Region:        orgProfile.Region,
+			AccountId:     account.Id,
+			AccountName:   account.Name,
+			OuPath:        account.OuPath,
+			sourceProfile: orgProfile.Name,
+			roleArn:       fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, account.Id, opts.RoleName),
+			externalId:    opts.ExternalId,
+		})
+	}
+
+	return orgProfiles, nil
+}
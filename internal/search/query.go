@@ -0,0 +1,458 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aviadhaham/cloudcate/internal/services"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// Fields are the values of a resource the query language matches, by field name. Tags are stored
+// under FieldTag as "Key=Value".
+type Fields map[string][]string
+
+// Query language fields
+const (
+	FieldId     = "id"
+	FieldName   = "name"
+	FieldArn    = "arn"
+	FieldState  = "state"
+	FieldType   = "type"
+	FieldIp     = "ip"
+	FieldCidr   = "cidr"
+	FieldDns    = "dns"
+	FieldVpc    = "vpc"
+	FieldSubnet = "subnet"
+	FieldUser   = "user"
+	FieldTag    = "tag"
+)
+
+var queryFields = map[string]bool{
+	FieldId: true, FieldName: true, FieldArn: true, FieldState: true, FieldType: true, FieldIp: true,
+	FieldCidr: true, FieldDns: true, FieldVpc: true, FieldSubnet: true, FieldUser: true, FieldTag: true,
+}
+
+// QueryFields lists the fields of the query language.
+func QueryFields() []string {
+	fields := make([]string, 0, len(queryFields))
+	for field := range queryFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Add appends the non-empty values to a field.
+func (f Fields) Add(field string, values ...string) {
+	for _, value := range values {
+		if value != "" {
+			f[field] = append(f[field], value)
+		}
+	}
+}
+
+// AddTag adds a tag to the fields.
+func (f Fields) AddTag(key string, value string) {
+	f[FieldTag] = append(f[FieldTag], key+"="+value)
+}
+
+// Expr is a parsed search term, matched against the resources of every type alike. fields are the Fields of
+// the resource, computed once per resource however many terms and expressions are matched against it.
+type Expr interface {
+	Eval(searcher Searcher, resource interface{}, fields Fields) bool
+}
+
+type andExpr struct{ left, right Expr }
+type orExpr struct{ left, right Expr }
+type notExpr struct{ expr Expr }
+
+// wordExpr is a bare word, matched the way the resource type matches free text.
+type wordExpr struct{ matcher services.Matcher }
+
+// fieldExpr is a field:value, field:~value or tag:Key=Value term.
+type fieldExpr struct {
+	field   string
+	matcher services.Matcher
+	// tagKey is the key of a tag term, whose matcher (if any) matches the value of the tag
+	tagKey     string
+	matchesTag bool
+	options    services.MatchOptions
+}
+
+func (e andExpr) Eval(searcher Searcher, resource interface{}, fields Fields) bool {
+	return e.left.Eval(searcher, resource, fields) && e.right.Eval(searcher, resource, fields)
+}
+
+func (e orExpr) Eval(searcher Searcher, resource interface{}, fields Fields) bool {
+	return e.left.Eval(searcher, resource, fields) || e.right.Eval(searcher, resource, fields)
+}
+
+func (e notExpr) Eval(searcher Searcher, resource interface{}, fields Fields) bool {
+	return !e.expr.Eval(searcher, resource, fields)
+}
+
+func (e wordExpr) Eval(searcher Searcher, resource interface{}, fields Fields) bool {
+	return searcher.Match(resource, e.matcher)
+}
+
+func (e fieldExpr) Eval(searcher Searcher, resource interface{}, fields Fields) bool {
+	switch e.field {
+	case FieldTag:
+		return e.matchTag(fields[FieldTag])
+	case FieldIp, FieldCidr:
+		// an IP address or a CIDR block is matched as a network, anything else as text
+		if matched, ok := e.matcher.MatchNetwork(fields[FieldIp], fields[FieldCidr]); ok {
+			return matched
+		}
+	}
+	return e.matcher.Match(fields[e.field]...)
+}
+
+// matchTag matches tag:Key (the key exists), tag:Key=Value and tag:Key=~Value.
+func (e fieldExpr) matchTag(tags []string) bool {
+	for _, tag := range tags {
+		tagKey, tagValue, _ := strings.Cut(tag, "=")
+		if !e.matchTagKey(tagKey) {
+			continue
+		}
+		if !e.matchesTag || e.matcher.Match(tagValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e fieldExpr) matchTagKey(key string) bool {
+	if e.options.CaseSensitive {
+		return key == e.tagKey
+	}
+	return strings.EqualFold(key, e.tagKey)
+}
+
+// matchedFields returns the fields of a resource an expression matched, sorted. Negated terms match no field,
+// and a bare word matches the fields with a value it matches, as text or as a network.
+func matchedFields(expr Expr, searcher Searcher, resource interface{}, fields Fields) []string {
+	matched := map[string]bool{}
+	collectMatchedFields(expr, searcher, resource, fields, matched)
+
+	names := make([]string, 0, len(matched))
+	for field := range matched {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func collectMatchedFields(expr Expr, searcher Searcher, resource interface{}, fields Fields, matched map[string]bool) {
+	switch e := expr.(type) {
+	case andExpr:
+		collectMatchedFields(e.left, searcher, resource, fields, matched)
+		collectMatchedFields(e.right, searcher, resource, fields, matched)
+	case orExpr:
+		collectMatchedFields(e.left, searcher, resource, fields, matched)
+		collectMatchedFields(e.right, searcher, resource, fields, matched)
+	case fieldExpr:
+		if e.Eval(searcher, resource, fields) {
+			matched[e.field] = true
+		}
+	case wordExpr:
+		for field, values := range fields {
+			if e.matchesField(field, values) {
+				matched[field] = true
+			}
+		}
+	}
+}
+
+// matchesField reports whether the word matches one of the values of a field, tags by their key or value.
+func (e wordExpr) matchesField(field string, values []string) bool {
+	if _, isNetwork := e.matcher.MatchNetwork(nil, nil); isNetwork {
+		switch field {
+		case FieldIp:
+			found, _ := e.matcher.MatchNetwork(values, nil)
+			return found
+		case FieldCidr:
+			found, _ := e.matcher.MatchNetwork(nil, values)
+			return found
+		}
+		return false
+	}
+
+	if field == FieldTag {
+		tags := map[string]string{}
+		for _, tag := range values {
+			key, value, _ := strings.Cut(tag, "=")
+			tags[key] = value
+		}
+		return e.matcher.MatchTags(tags)
+	}
+	return e.matcher.Match(values...)
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+func (t token) is(operator string) bool {
+	return !t.quoted && t.text == operator
+}
+
+// fieldOf returns the field of a field:value token, false if the token is a bare word.
+func (t token) fieldOf() (string, string, bool) {
+	if t.quoted {
+		return "", "", false
+	}
+	field, value, ok := strings.Cut(t.text, ":")
+	if !ok || !queryFields[strings.ToLower(field)] {
+		return "", "", false
+	}
+	// a bare ARN is a word, not the arn field
+	if arn.IsARN(t.text) && strings.ToLower(field) == FieldArn {
+		if _, err := arn.Parse(t.text); err == nil {
+			return "", "", false
+		}
+	}
+	return strings.ToLower(field), value, true
+}
+
+// tokenize splits a term on whitespace and parentheses, double quotes keep spaces in a value (name:"my app").
+func tokenize(term string) ([]token, error) {
+	tokens := []token{}
+	var current strings.Builder
+	inQuotes, quoted := false, false
+
+	flush := func() {
+		if current.Len() > 0 || quoted {
+			tokens = append(tokens, token{text: current.String(), quoted: quoted})
+		}
+		current.Reset()
+		quoted = false
+	}
+
+	for _, r := range term {
+		switch {
+		case r == '"':
+			// only a token that starts with a quote is a quoted word, name:"my app" is still a field
+			if !inQuotes && current.Len() == 0 {
+				quoted = true
+			}
+			inQuotes = !inQuotes
+		case inQuotes:
+			current.WriteRune(r)
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, token{text: string(r)})
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in %q", term)
+	}
+	flush()
+	return tokens, nil
+}
+
+// usesQuerySyntax reports whether a term uses the query language. A term without fields, operators,
+// parentheses or quotes is a single free text word, spaces included, as it always was. A lone AND, OR or NOT
+// operates on nothing, it is searched as a word.
+func usesQuerySyntax(tokens []token) bool {
+	for _, t := range tokens {
+		if t.is("AND") || t.is("OR") || t.is("NOT") {
+			if len(tokens) > 1 {
+				return true
+			}
+			continue
+		}
+		if t.quoted || t.is("(") || t.is(")") {
+			return true
+		}
+		if _, _, ok := strings.Cut(t.text, ":"); ok {
+			if _, _, isField := t.fieldOf(); isField {
+				return true
+			}
+			if strings.HasPrefix(t.text, "-") {
+				if _, _, isField := (token{text: t.text[1:]}).fieldOf(); isField {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ParseTerm parses a search term, such as
+//
+//	tag:Team=payments state:running name:~api- ip:10.2.0.0/16
+//
+// Terms are ANDed unless joined by OR, NOT (or a leading -) negates a term and parentheses group them.
+// field:value matches a field exactly, field:~value matches it with the match mode of the options (a part
+// of it by default), ip and cidr values are matched as networks (they must be IP addresses or CIDR blocks,
+// unless they use ~), and bare words are matched as free text with the match mode.
+func ParseTerm(term string, options services.MatchOptions) (Expr, error) {
+	tokens, err := tokenize(term)
+	if err != nil {
+		return nil, err
+	}
+	if !usesQuerySyntax(tokens) {
+		return newWordExpr(term, options)
+	}
+
+	p := &parser{tokens: tokens, options: options}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos].text, term)
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens  []token
+	pos     int
+	options services.MatchOptions
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || !t.is("OR") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.is("OR") || t.is(")") {
+			return left, nil
+		}
+		if t.is("AND") {
+			p.pos++
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of the search term")
+	}
+	if t.is("NOT") {
+		p.pos++
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+	}
+	// -field:value is a shorthand for NOT field:value
+	if !t.quoted && strings.HasPrefix(t.text, "-") {
+		if field, value, ok := (token{text: t.text[1:]}).fieldOf(); ok {
+			p.pos++
+			expr, err := newFieldExpr(field, value, p.options)
+			if err != nil {
+				return nil, err
+			}
+			return notExpr{expr: expr}, nil
+		}
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t, _ := p.peek()
+	p.pos++
+
+	switch {
+	case t.is("("):
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || !closing.is(")") {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return expr, nil
+	case t.is(")"), t.is("AND"), t.is("OR"):
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+
+	if field, value, ok := t.fieldOf(); ok {
+		return newFieldExpr(field, value, p.options)
+	}
+	return newWordExpr(t.text, p.options)
+}
+
+func newWordExpr(word string, options services.MatchOptions) (Expr, error) {
+	matcher, err := services.NewMatcher(word, options)
+	if err != nil {
+		return nil, err
+	}
+	return wordExpr{matcher: matcher}, nil
+}
+
+// newFieldExpr matches value exactly, or with the match mode of the options when it starts with ~.
+func newFieldExpr(field string, value string, options services.MatchOptions) (Expr, error) {
+	expr := fieldExpr{field: field, options: options}
+	if field == FieldTag {
+		expr.tagKey, value, expr.matchesTag = strings.Cut(value, "=")
+		if !expr.matchesTag {
+			return expr, nil
+		}
+	}
+
+	valueOptions := services.MatchOptions{Mode: services.MatchExact, CaseSensitive: options.CaseSensitive}
+	partial := strings.HasPrefix(value, "~")
+	if partial {
+		value, valueOptions = value[1:], options
+	}
+
+	matcher, err := services.NewMatcher(value, valueOptions)
+	if err != nil {
+		return nil, err
+	}
+	// an exact ip or cidr value that isn't a network would only match the very same text, never a part of an
+	// address like the bare word does
+	if _, isNetwork := matcher.MatchNetwork(nil, nil); (field == FieldIp || field == FieldCidr) && !partial && !isNetwork {
+		return nil, fmt.Errorf("%s:%s is not an IP address or a CIDR block, use %s:~%s to match it as text", field, value, field, value)
+	}
+	expr.matcher = matcher
+	return expr, nil
+}
@@ -0,0 +1,172 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/aviadhaham/cloudcate/internal/services"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// fieldsSearcher searches resources that are their own Fields, bare words match their names.
+type fieldsSearcher struct{}
+
+func (fieldsSearcher) Type() string       { return "fields_test" }
+func (fieldsSearcher) SubTypes() []string { return nil }
+func (fieldsSearcher) Global() bool       { return false }
+
+func (fieldsSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	return nil, nil
+}
+
+func (fieldsSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return matcher.Match(resource.(Fields)[FieldName]...)
+}
+
+func (fieldsSearcher) Fields(resource interface{}) Fields {
+	return resource.(Fields)
+}
+
+func (fieldsSearcher) Result(resource interface{}, scope Scope) Resource {
+	return scope.RegionalResource()
+}
+
+var queryTestResources = map[string]Fields{
+	"web": {
+		FieldName:  {"web app"},
+		FieldState: {"running"},
+		FieldTag:   {"Team=payments", "Env=prod"},
+		FieldIp:    {"10.2.0.5"},
+		FieldVpc:   {"vpc-1"},
+	},
+	"db": {
+		FieldName:  {"db-1"},
+		FieldState: {"stopped"},
+		FieldTag:   {"Team=data"},
+		FieldIp:    {"10.3.0.5"},
+		FieldVpc:   {"vpc-1"},
+	},
+	"notes": {
+		FieldName:  {"NOT-READY"},
+		FieldState: {"running"},
+		FieldCidr:  {"10.2.0.0/24"},
+	},
+}
+
+func TestParseTerm(t *testing.T) {
+	tests := []struct {
+		name    string
+		term    string
+		options services.MatchOptions
+		want    []string
+		wantErr bool
+	}{
+		{name: "bare word", term: "web", want: []string{"web"}},
+		{name: "bare word is a substring", term: "EB AP", want: []string{"web"}},
+		{name: "bare NOT is a word", term: "NOT", want: []string{"notes"}},
+		{name: "bare AND is a word", term: "AND", want: []string{}},
+		{name: "bare OR is a word", term: "OR", want: []string{}},
+		{name: "lowercase operator is a word", term: "not", want: []string{"notes"}},
+		{name: "field is exact", term: "state:running", want: []string{"notes", "web"}},
+		{name: "field name is case-insensitive", term: "STATE:running", want: []string{"notes", "web"}},
+		{name: "field value is not a substring", term: "state:run", want: []string{}},
+		{name: "field value with ~ uses the match mode", term: "state:~run", want: []string{"notes", "web"}},
+		{name: "field value with ~ uses the prefix mode", term: "name:~db", options: services.MatchOptions{Mode: services.MatchPrefix}, want: []string{"db"}},
+		{name: "tag key", term: "tag:Env", want: []string{"web"}},
+		{name: "tag key and value", term: "tag:Team=data", want: []string{"db"}},
+		{name: "tag value with ~", term: "tag:team=~pay", want: []string{"web"}},
+		{name: "ip in network", term: "ip:10.2.0.0/16", want: []string{"notes", "web"}},
+		{name: "ip address", term: "ip:10.3.0.5", want: []string{"db"}},
+		{name: "ip as text with ~", term: "ip:~10.3", want: []string{"db"}},
+		{name: "terms are ANDed", term: "state:running vpc:vpc-1", want: []string{"web"}},
+		{name: "explicit AND", term: "state:running AND vpc:vpc-1", want: []string{"web"}},
+		{name: "OR", term: "name:db-1 OR tag:Env", want: []string{"db", "web"}},
+		{name: "AND binds tighter than OR", term: "name:db-1 state:running OR name:NOT-READY", want: []string{"notes"}},
+		{name: "NOT binds tighter than OR", term: "NOT state:running OR tag:Env", want: []string{"db", "web"}},
+		{name: "NOT binds tighter than AND", term: "NOT state:stopped vpc:vpc-1", want: []string{"web"}},
+		{name: "- negates a field", term: "-state:running", want: []string{"db"}},
+		{name: "parentheses group", term: "(name:db-1 OR name:NOT-READY) state:running", want: []string{"notes"}},
+		{name: "negated group", term: "NOT (state:stopped OR tag:Env)", want: []string{"notes"}},
+		{name: "nested parentheses", term: "((tag:Team=data))", want: []string{"db"}},
+		{name: "quoted field value", term: `name:"web app"`, want: []string{"web"}},
+		{name: "quoted word", term: `"web app" state:running`, want: []string{"web"}},
+		{name: "quoted operator is a word", term: `"NOT" state:running`, want: []string{"notes"}},
+		{name: "quoted field is a word", term: `"state:running"`, want: []string{}},
+		{name: "unknown field is a word", term: "color:red", want: []string{}},
+		{name: "unknown field next to a field is a word", term: "db-1 color:red OR state:stopped", want: []string{"db"}},
+		{name: "bare ARN is a word", term: "arn:aws:ec2:eu-west-1:123456789012:instance/i-0abc", want: []string{}},
+		{name: "unterminated quote", term: `name:"web app`, wantErr: true},
+		{name: "missing closing parenthesis", term: "(state:running", wantErr: true},
+		{name: "unexpected closing parenthesis", term: "state:running)", wantErr: true},
+		{name: "empty parentheses", term: "()", wantErr: true},
+		{name: "trailing OR", term: "state:running OR", wantErr: true},
+		{name: "leading AND", term: "AND state:running", wantErr: true},
+		{name: "trailing NOT", term: "state:running NOT", wantErr: true},
+		{name: "ip that is not a network", term: "ip:web", wantErr: true},
+		{name: "invalid regular expression", term: "name:~[", options: services.MatchOptions{Mode: services.MatchRegex}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseTerm(tt.term, tt.options)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTerm(%q) succeeded, want an error", tt.term)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTerm(%q): %v", tt.term, err)
+			}
+
+			got := []string{}
+			for name, resource := range queryTestResources {
+				if expr.Eval(fieldsSearcher{}, resource, resource) {
+					got = append(got, name)
+				}
+			}
+			sort.Strings(got)
+			if !equalStrings(got, tt.want) {
+				t.Errorf("ParseTerm(%q) matched %v, want %v", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchedFields(t *testing.T) {
+	tests := []struct {
+		term string
+		want []string
+	}{
+		{term: "state:running tag:Env", want: []string{FieldState, FieldTag}},
+		{term: "state:running OR name:nope", want: []string{FieldState}},
+		{term: "state:running -tag:Team=data", want: []string{FieldState}},
+		{term: "10.2.0.0/16", want: []string{FieldIp}},
+		{term: "payments", want: []string{FieldTag}},
+	}
+
+	resource := queryTestResources["web"]
+	for _, tt := range tests {
+		expr, err := ParseTerm(tt.term, services.MatchOptions{})
+		if err != nil {
+			t.Fatalf("ParseTerm(%q): %v", tt.term, err)
+		}
+		if got := matchedFields(expr, fieldsSearcher{}, resource, resource); !equalStrings(got, tt.want) {
+			t.Errorf("matchedFields(%q) = %v, want %v", tt.term, got, tt.want)
+		}
+	}
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
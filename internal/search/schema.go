@@ -0,0 +1,98 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// ResourceSchema returns the JSON Schema of the search results, whose attributes are one of the attribute
+// types of the registered searchers (see Describer).
+func ResourceSchema() map[string]interface{} {
+	defs := map[string]interface{}{}
+	attributes := []interface{}{}
+	for _, searcher := range Searchers() {
+		describer, ok := searcher.(Describer)
+		if !ok {
+			continue
+		}
+		for _, attributeType := range describer.AttributeTypes() {
+			t := reflect.TypeOf(attributeType)
+			defs[t.Name()] = typeSchema(t)
+			attributes = append(attributes, map[string]interface{}{"$ref": "#/$defs/" + t.Name()})
+		}
+	}
+
+	schema := typeSchema(reflect.TypeOf(Resource{}))
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = "Resource"
+	schema["$defs"] = defs
+
+	properties := schema["properties"].(map[string]interface{})
+	properties["attributes"] = map[string]interface{}{
+		"description": "The attributes specific to the resource type",
+		"anyOf":       attributes,
+	}
+	properties["raw"] = map[string]interface{}{
+		"description": "The resource as returned by AWS, only with raw=true",
+	}
+	return schema
+}
+
+// typeSchema describes a Go type the way encoding/json marshals it.
+func typeSchema(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	// interface{} holds any value
+	return map[string]interface{}{}
+}
+
+// structSchema describes the JSON fields of a struct, the fields without omitempty are required.
+func structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = typeSchema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
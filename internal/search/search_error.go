@@ -0,0 +1,145 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/aviadhaham/cloudcate/internal/services"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/smithy-go"
+)
+
+// Error classes reported in SearchError.Class
+const (
+	ErrorClassAccessDenied       = "access_denied"
+	ErrorClassThrottled          = "throttled"
+	ErrorClassExpiredCredentials = "expired_credentials"
+	ErrorClassTimeout            = "timeout"
+	ErrorClassUnknown            = "unknown"
+)
+
+// SearchError describes a profile, region or service that could not be searched.
+// Errors are reported next to the results instead of failing the whole search.
+type SearchError struct {
+	Profile string `json:"profile"`
+	Account string `json:"account,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Service string `json:"service,omitempty"`
+	Class   string `json:"class"`
+	Message string `json:"message"`
+}
+
+// Truncation records a listing that stopped at the page cap, so some resources may be missing from the results.
+type Truncation struct {
+	Profile string `json:"profile"`
+	Account string `json:"account,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Service string `json:"service,omitempty"`
+	Message string `json:"message"`
+}
+
+var accessDeniedErrorCodes = map[string]struct{}{
+	"AccessDenied":          {},
+	"AccessDeniedException": {},
+	"UnauthorizedOperation": {},
+	"UnauthorizedAccess":    {},
+	"AuthorizationError":    {},
+	"AuthFailure":           {},
+	"OptInRequired":         {},
+}
+
+// expired or otherwise no longer usable credentials
+var expiredCredentialsErrorCodes = map[string]struct{}{
+	"InvalidClientTokenId":        {},
+	"UnrecognizedClientException": {},
+	"ExpiredToken":                {},
+	"ExpiredTokenException":       {},
+	"RequestExpired":              {},
+	"TokenRefreshRequired":        {},
+}
+
+func newSearchError(scope Scope, service string, err error) SearchError {
+	return SearchError{
+		Profile: scope.Profile,
+		Account: scope.Account.AccountId,
+		Region:  scope.Region,
+		Service: service,
+		Class:   classifyError(err),
+		Message: err.Error(),
+	}
+}
+
+func newTruncation(scope Scope, service string, err error) Truncation {
+	return Truncation{
+		Profile: scope.Profile,
+		Account: scope.Account.AccountId,
+		Region:  scope.Region,
+		Service: service,
+		Message: err.Error(),
+	}
+}
+
+// splitTruncation separates page cap notices from actual failures in a fetch error,
+// which may join several errors (e.g. one API failed while another one was truncated).
+func splitTruncation(err error) (truncatedErr error, failedErr error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	var truncated, failed []error
+	for _, e := range errs {
+		if errors.Is(e, services.ErrTruncated) {
+			truncated = append(truncated, e)
+		} else {
+			failed = append(failed, e)
+		}
+	}
+	return errors.Join(truncated...), errors.Join(failed...)
+}
+
+// classifyError maps an AWS SDK error to one of the ErrorClass constants.
+func classifyError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return ErrorClassTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTimeout
+	}
+
+	var tokenErr *ssocreds.InvalidTokenError
+	if errors.As(err, &tokenErr) {
+		return ErrorClassExpiredCredentials
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		if _, ok := expiredCredentialsErrorCodes[code]; ok {
+			return ErrorClassExpiredCredentials
+		}
+		if _, ok := retry.DefaultThrottleErrorCodes[code]; ok {
+			return ErrorClassThrottled
+		}
+		if _, ok := accessDeniedErrorCodes[code]; ok {
+			return ErrorClassAccessDenied
+		}
+	}
+
+	var responseErr *http.ResponseError
+	if errors.As(err, &responseErr) {
+		switch responseErr.HTTPStatusCode() {
+		case 403:
+			return ErrorClassAccessDenied
+		case 429:
+			return ErrorClassThrottled
+		}
+	}
+
+	return ErrorClassUnknown
+}
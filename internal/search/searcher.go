@@ -0,0 +1,215 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aviadhaham/cloudcate/internal/services"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// Searcher knows how to list and match a single resource type.
+// Built-in searchers are registered in searchers.go, additional ones can be plugged in with Register.
+type Searcher interface {
+	// Type is the resource_type value the searcher is registered under.
+	Type() string
+	// SubTypes lists the accepted resource_subtype values, empty if the type has none.
+	SubTypes() []string
+	// Global reports whether the resource type is global, so a single region is enough to find it.
+	Global() bool
+	// Fetch lists every resource of the type (and subtype) in the given region, walking at most maxPages pages
+	// per paginated call (zero means no cap). When the listing only partially failed or stopped at the page cap
+	// (services.ErrTruncated), the resources that were listed are returned along with the error.
+	Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error)
+	// Match reports whether a fetched resource matches a free text search value.
+	Match(resource interface{}, matcher services.Matcher) bool
+	// Fields returns the values of a fetched resource the query language matches (see ParseTerm).
+	Fields(resource interface{}) Fields
+	// Result converts a matched resource into its search result.
+	Result(resource interface{}, scope Scope) Resource
+}
+
+// Decoder is implemented by searchers whose fetched resources can be stored in the index: Decode turns the
+// JSON of a Fetch result back into the resources Match and Result expect. Types without it are always searched live.
+type Decoder interface {
+	Decode(resourceSubType string, data []byte) ([]interface{}, error)
+}
+
+// Identifier is implemented by searchers whose resources have a stable ID, so crawl snapshots can tell
+// which resources were added, removed or modified. Types without it are left out of the snapshots.
+type Identifier interface {
+	ResourceId(resource interface{}) string
+}
+
+// Describer is implemented by searchers that publish the attributes of their results, one zero value per
+// attributes type, so the JSON Schema of the results can describe them (see ResourceSchema).
+type Describer interface {
+	AttributeTypes() []interface{}
+}
+
+// Explicit is implemented by searchers that are only searched when their resource type is asked for, not by the
+// searches of every type ("all"), because their results duplicate the ones of the other types.
+type Explicit interface {
+	Explicit() bool
+}
+
+// ArnDescriber is implemented by searchers that can describe a single resource by its ARN (see DescribeArn).
+type ArnDescriber interface {
+	// DescribesArn reports whether the ARN is one of the searcher's resources, and the subtype of the resource.
+	DescribesArn(resourceArn services.ResourceArn) (resourceSubType string, ok bool)
+	// DescribeArn describes the resource in the given region, it returns the resource Match and Result expect,
+	// or an error wrapping services.ErrNotFound when the resource doesn't exist.
+	DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error)
+}
+
+// Scope identifies where a resource was found.
+type Scope struct {
+	Profile         string
+	Profiles        []string
+	Account         AccountIdentity
+	OuPath          string
+	Region          string
+	ResourceType    string
+	ResourceSubType string
+}
+
+// GlobalResource returns the envelope of a global resource found in the scope, it has no region.
+func (s Scope) GlobalResource() Resource {
+	return Resource{
+		ResourceType:    s.ResourceType,
+		ResourceSubType: s.ResourceSubType,
+		AccountId:       s.Account.AccountId,
+		AccountAlias:    s.Account.AccountAlias,
+		AccountName:     s.Account.AccountName,
+		OuPath:          s.OuPath,
+		Profile:         s.Profile,
+		Profiles:        s.Profiles,
+	}
+}
+
+// RegionalResource returns the envelope of a resource found in the region of the scope.
+func (s Scope) RegionalResource() Resource {
+	resource := s.GlobalResource()
+	resource.Region = s.Region
+	return resource
+}
+
+// Arn returns the ARN of a resource of the account in the region of the scope, for the resources whose
+// listing doesn't include their ARN. It is empty when the account is unknown.
+func (s Scope) Arn(service string, resource string) string {
+	if s.Account.AccountId == "" {
+		return ""
+	}
+	return arn.ARN{
+		Partition: partitionOf(s.Region),
+		Service:   service,
+		Region:    s.Region,
+		AccountID: s.Account.AccountId,
+		Resource:  resource,
+	}.String()
+}
+
+// partitionOf returns the partition of a region, e.g. aws-cn for cn-north-1.
+func partitionOf(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "us-isob-"):
+		return "aws-iso-b"
+	case strings.HasPrefix(region, "us-iso-"):
+		return "aws-iso"
+	}
+	return "aws"
+}
+
+// globalRegionOf returns the region serving the global services of a partition (IAM, Route 53, CloudFront),
+// e.g. us-east-1 for aws.
+func globalRegionOf(partition string) string {
+	switch partition {
+	case "aws-cn":
+		return "cn-north-1"
+	case "aws-us-gov":
+		return "us-gov-west-1"
+	case "aws-iso":
+		return "us-iso-east-1"
+	case "aws-iso-b":
+		return "us-isob-east-1"
+	}
+	return "us-east-1"
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Searcher{}
+)
+
+// Register makes a searcher available under its resource type. It panics if the type is already registered.
+func Register(s Searcher) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[s.Type()]; exists {
+		panic(fmt.Sprintf("search: searcher for resource type %q is already registered", s.Type()))
+	}
+	registry[s.Type()] = s
+}
+
+// Lookup returns the searcher registered for the resource type.
+func Lookup(resourceType string) (Searcher, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	s, ok := registry[resourceType]
+	return s, ok
+}
+
+// Searchers returns every registered searcher, ordered by resource type.
+func Searchers() []Searcher {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	searchers := make([]Searcher, 0, len(registry))
+	for _, s := range registry {
+		searchers = append(searchers, s)
+	}
+	sort.Slice(searchers, func(i, j int) bool {
+		return searchers[i].Type() < searchers[j].Type()
+	})
+	return searchers
+}
+
+// allSearchers returns the searchers of the searches of every type, ordered by resource type.
+func allSearchers() []Searcher {
+	searchers := []Searcher{}
+	for _, s := range Searchers() {
+		if explicit, ok := s.(Explicit); ok && explicit.Explicit() {
+			continue
+		}
+		searchers = append(searchers, s)
+	}
+	return searchers
+}
+
+func decodeResources[T any](data []byte) ([]interface{}, error) {
+	var items []T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	return toInterfaces(items), nil
+}
+
+func toInterfaces[T any](items []T) []interface{} {
+	result := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		result = append(result, item)
+	}
+	return result
+}
@@ -0,0 +1,895 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aviadhaham/cloudcate/internal/services"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	ec2_types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	iam_types "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+func init() {
+	Register(vpcSearcher{})
+	Register(subnetSearcher{})
+	Register(loadBalancerSearcher{})
+	Register(ec2Searcher{})
+	Register(s3Searcher{})
+	Register(dnsSearcher{})
+	Register(iamSearcher{})
+	Register(elasticIpSearcher{})
+	Register(cloudfrontSearcher{})
+	Register(ipSearcher{})
+	Register(tagsSearcher{})
+}
+
+func nameTag(tags []ec2_types.Tag) string {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == "Name" {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
+
+// ec2Fields adds the EC2 tags of a resource, and its Name tag as its name.
+func ec2Fields(fields Fields, tags []ec2_types.Tag) Fields {
+	fields.Add(FieldName, nameTag(tags))
+	return tagFields(fields, services.Ec2Tags(tags))
+}
+
+// tagFields adds the tags of a resource to its fields.
+func tagFields(fields Fields, tags map[string]string) Fields {
+	for key, value := range tags {
+		fields.AddTag(key, value)
+	}
+	return fields
+}
+
+type vpcSearcher struct{}
+
+func (vpcSearcher) Type() string       { return "vpc" }
+func (vpcSearcher) SubTypes() []string { return nil }
+func (vpcSearcher) Global() bool       { return false }
+
+func (vpcSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	vpcs, err := services.ListVpcs(ctx, cfg, region, maxPages)
+	if err != nil {
+		return toInterfaces(vpcs), fmt.Errorf("error finding VPCs: %w", err)
+	}
+	return toInterfaces(vpcs), nil
+}
+
+func (vpcSearcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[ec2_types.Vpc](data)
+}
+
+func (vpcSearcher) ResourceId(resource interface{}) string {
+	return aws.ToString(resource.(ec2_types.Vpc).VpcId)
+}
+
+func (vpcSearcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	return "", resourceArn.Service == "ec2" && resourceArn.ResourceType == "vpc"
+}
+
+func (vpcSearcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	return services.GetVpc(ctx, cfg, region, resourceArn.ResourceId)
+}
+
+func (vpcSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchVpc(resource.(ec2_types.Vpc), matcher)
+}
+
+func (vpcSearcher) Fields(resource interface{}) Fields {
+	vpc := resource.(ec2_types.Vpc)
+	fields := Fields{}
+	fields.Add(FieldId, aws.ToString(vpc.VpcId))
+	fields.Add(FieldVpc, aws.ToString(vpc.VpcId))
+	fields.Add(FieldState, string(vpc.State))
+	fields.Add(FieldCidr, services.VpcCidrBlocks(vpc)...)
+	return ec2Fields(fields, vpc.Tags)
+}
+
+func (vpcSearcher) AttributeTypes() []interface{} { return []interface{}{VpcAttributes{}} }
+
+func (vpcSearcher) Result(resource interface{}, scope Scope) Resource {
+	vpc := resource.(ec2_types.Vpc)
+	result := scope.RegionalResource()
+	result.Id = aws.ToString(vpc.VpcId)
+	result.Arn = scope.Arn("ec2", "vpc/"+result.Id)
+	result.Name = nameTag(vpc.Tags)
+	result.Tags = services.Ec2Tags(vpc.Tags)
+	result.Attributes = VpcAttributes{
+		VpcId:     aws.ToString(vpc.VpcId),
+		CidrBlock: aws.ToString(vpc.CidrBlock),
+		State:     string(vpc.State),
+	}
+	return result
+}
+
+type subnetSearcher struct{}
+
+func (subnetSearcher) Type() string       { return "subnet" }
+func (subnetSearcher) SubTypes() []string { return nil }
+func (subnetSearcher) Global() bool       { return false }
+
+func (subnetSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	subnets, err := services.ListSubnets(ctx, cfg, region, maxPages)
+	if err != nil {
+		return toInterfaces(subnets), fmt.Errorf("error finding subnets: %w", err)
+	}
+	return toInterfaces(subnets), nil
+}
+
+func (subnetSearcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[ec2_types.Subnet](data)
+}
+
+func (subnetSearcher) ResourceId(resource interface{}) string {
+	return aws.ToString(resource.(ec2_types.Subnet).SubnetId)
+}
+
+func (subnetSearcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	return "", resourceArn.Service == "ec2" && resourceArn.ResourceType == "subnet"
+}
+
+func (subnetSearcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	return services.GetSubnet(ctx, cfg, region, resourceArn.ResourceId)
+}
+
+func (subnetSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchSubnet(resource.(ec2_types.Subnet), matcher)
+}
+
+func (subnetSearcher) Fields(resource interface{}) Fields {
+	subnet := resource.(ec2_types.Subnet)
+	fields := Fields{}
+	fields.Add(FieldId, aws.ToString(subnet.SubnetId))
+	fields.Add(FieldSubnet, aws.ToString(subnet.SubnetId))
+	fields.Add(FieldArn, aws.ToString(subnet.SubnetArn))
+	fields.Add(FieldVpc, aws.ToString(subnet.VpcId))
+	fields.Add(FieldState, string(subnet.State))
+	fields.Add(FieldCidr, services.SubnetCidrBlocks(subnet)...)
+	return ec2Fields(fields, subnet.Tags)
+}
+
+func (subnetSearcher) AttributeTypes() []interface{} { return []interface{}{SubnetAttributes{}} }
+
+func (subnetSearcher) Result(resource interface{}, scope Scope) Resource {
+	subnet := resource.(ec2_types.Subnet)
+	result := scope.RegionalResource()
+	result.Id = aws.ToString(subnet.SubnetId)
+	result.Arn = aws.ToString(subnet.SubnetArn)
+	result.Name = nameTag(subnet.Tags)
+	result.Tags = services.Ec2Tags(subnet.Tags)
+	result.Attributes = SubnetAttributes{
+		SubnetId:         aws.ToString(subnet.SubnetId),
+		VpcId:            aws.ToString(subnet.VpcId),
+		CidrBlocks:       services.SubnetCidrBlocks(subnet),
+		AvailabilityZone: aws.ToString(subnet.AvailabilityZone),
+	}
+	return result
+}
+
+type loadBalancerSearcher struct{}
+
+func (loadBalancerSearcher) Type() string       { return "loadbalancer" }
+func (loadBalancerSearcher) SubTypes() []string { return nil }
+func (loadBalancerSearcher) Global() bool       { return false }
+
+func (loadBalancerSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	// the error is returned as is, it may join a failure of one API with the truncation of the other
+	loadBalancers, err := services.ListLoadBalancers(ctx, cfg, region, maxPages)
+	return toInterfaces(loadBalancers), err
+}
+
+func (loadBalancerSearcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[services.LoadBalancer](data)
+}
+
+func (loadBalancerSearcher) ResourceId(resource interface{}) string {
+	lb := resource.(services.LoadBalancer)
+	// classic load balancers have no ARN, their name is unique in the region
+	if lb.Arn == "" {
+		return lb.Name
+	}
+	return lb.Arn
+}
+
+func (loadBalancerSearcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	return "", resourceArn.Service == "elasticloadbalancing" && resourceArn.ResourceType == "loadbalancer"
+}
+
+func (loadBalancerSearcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	// application, network and gateway load balancers are described by their ARN, loadbalancer/app/name/id,
+	// classic ones by their name, loadbalancer/name
+	if !strings.Contains(resourceArn.ResourceId, "/") {
+		return services.GetLoadBalancer(ctx, cfg, region, resourceArn.ResourceId)
+	}
+	return services.GetLoadBalancer(ctx, cfg, region, resourceArn.Arn)
+}
+
+func (loadBalancerSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchLoadBalancer(resource.(services.LoadBalancer), matcher)
+}
+
+func (loadBalancerSearcher) Fields(resource interface{}) Fields {
+	lb := resource.(services.LoadBalancer)
+	fields := Fields{}
+	fields.Add(FieldId, loadBalancerSearcher{}.ResourceId(lb))
+	fields.Add(FieldName, lb.Name)
+	fields.Add(FieldArn, lb.Arn)
+	fields.Add(FieldDns, lb.DnsName)
+	return tagFields(fields, lb.Tags)
+}
+
+func (loadBalancerSearcher) AttributeTypes() []interface{} {
+	return []interface{}{LoadBalancerAttributes{}}
+}
+
+func (loadBalancerSearcher) Result(resource interface{}, scope Scope) Resource {
+	lb := resource.(services.LoadBalancer)
+	result := scope.RegionalResource()
+	result.Id = loadBalancerSearcher{}.ResourceId(lb)
+	result.Arn = lb.Arn
+	if result.Arn == "" {
+		result.Arn = scope.Arn("elasticloadbalancing", "loadbalancer/"+lb.Name)
+	}
+	result.Name = lb.Name
+	result.Tags = lb.Tags
+	result.CreatedAt = lb.CreatedTime
+	result.Attributes = LoadBalancerAttributes{
+		LoadBalancerName:    lb.Name,
+		LoadBalancerDnsName: lb.DnsName,
+	}
+	return result
+}
+
+type ec2Searcher struct{}
+
+func (ec2Searcher) Type() string       { return "ec2" }
+func (ec2Searcher) SubTypes() []string { return nil }
+func (ec2Searcher) Global() bool       { return false }
+
+func (ec2Searcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	instances, err := services.ListEc2Instances(ctx, cfg, region, maxPages)
+	if err != nil {
+		return toInterfaces(instances), fmt.Errorf("error finding EC2 instances: %w", err)
+	}
+	return toInterfaces(instances), nil
+}
+
+func (ec2Searcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[ec2_types.Instance](data)
+}
+
+func (ec2Searcher) ResourceId(resource interface{}) string {
+	return aws.ToString(resource.(ec2_types.Instance).InstanceId)
+}
+
+func (ec2Searcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	return "", resourceArn.Service == "ec2" && resourceArn.ResourceType == "instance"
+}
+
+func (ec2Searcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	return services.GetEc2Instance(ctx, cfg, region, resourceArn.ResourceId)
+}
+
+func (ec2Searcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchEc2(resource.(ec2_types.Instance), matcher)
+}
+
+func (ec2Searcher) Fields(resource interface{}) Fields {
+	instance := resource.(ec2_types.Instance)
+	fields := Fields{}
+	fields.Add(FieldId, aws.ToString(instance.InstanceId))
+	if instance.State != nil {
+		fields.Add(FieldState, string(instance.State.Name))
+	}
+	fields.Add(FieldType, string(instance.InstanceType))
+	fields.Add(FieldIp, services.Ec2InstanceAddresses(instance)...)
+	fields.Add(FieldDns, aws.ToString(instance.PrivateDnsName), aws.ToString(instance.PublicDnsName))
+	fields.Add(FieldVpc, aws.ToString(instance.VpcId))
+	fields.Add(FieldSubnet, aws.ToString(instance.SubnetId))
+	return ec2Fields(fields, instance.Tags)
+}
+
+func (ec2Searcher) AttributeTypes() []interface{} { return []interface{}{Ec2Attributes{}} }
+
+func (ec2Searcher) Result(resource interface{}, scope Scope) Resource {
+	instance := resource.(ec2_types.Instance)
+	result := scope.RegionalResource()
+	result.Id = aws.ToString(instance.InstanceId)
+	result.Arn = scope.Arn("ec2", "instance/"+result.Id)
+	result.Name = nameTag(instance.Tags)
+	result.Tags = services.Ec2Tags(instance.Tags)
+	result.CreatedAt = instance.LaunchTime
+	attributes := Ec2Attributes{
+		InstanceId:       aws.ToString(instance.InstanceId),
+		InstanceType:     string(instance.InstanceType),
+		PrivateIpAddress: aws.ToString(instance.PrivateIpAddress),
+		PrivateDnsName:   aws.ToString(instance.PrivateDnsName),
+		PublicDnsName:    aws.ToString(instance.PublicDnsName),
+		PublicIpAddress:  aws.ToString(instance.PublicIpAddress),
+		VpcId:            aws.ToString(instance.VpcId),
+		SubnetId:         aws.ToString(instance.SubnetId),
+	}
+	if instance.State != nil {
+		attributes.State = string(instance.State.Name)
+	}
+	result.Attributes = attributes
+	return result
+}
+
+type s3Searcher struct{}
+
+func (s3Searcher) Type() string       { return "s3" }
+func (s3Searcher) SubTypes() []string { return nil }
+func (s3Searcher) Global() bool       { return true }
+
+func (s3Searcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	buckets, err := services.ListS3Buckets(ctx, cfg, region)
+	if err != nil {
+		return nil, fmt.Errorf("error finding S3 buckets: %w", err)
+	}
+	return toInterfaces(buckets), nil
+}
+
+func (s3Searcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[services.S3Bucket](data)
+}
+
+func (s3Searcher) ResourceId(resource interface{}) string {
+	return aws.ToString(resource.(services.S3Bucket).Name)
+}
+
+func (s3Searcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	return "", resourceArn.Service == "s3" && resourceArn.ResourceType == "bucket"
+}
+
+func (s3Searcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	return services.GetS3Bucket(ctx, cfg, region, resourceArn.ResourceId)
+}
+
+func (s3Searcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchS3Bucket(resource.(services.S3Bucket), matcher)
+}
+
+func (s3Searcher) Fields(resource interface{}) Fields {
+	bucket := resource.(services.S3Bucket)
+	fields := Fields{}
+	fields.Add(FieldId, aws.ToString(bucket.Name))
+	fields.Add(FieldName, aws.ToString(bucket.Name))
+	return tagFields(fields, bucket.Tags)
+}
+
+func (s3Searcher) AttributeTypes() []interface{} { return []interface{}{S3Attributes{}} }
+
+func (s3Searcher) Result(resource interface{}, scope Scope) Resource {
+	bucket := resource.(services.S3Bucket)
+	result := scope.GlobalResource()
+	result.Id = aws.ToString(bucket.Name)
+	// bucket ARNs have neither a region nor an account
+	result.Arn = arn.ARN{Partition: partitionOf(scope.Region), Service: "s3", Resource: result.Id}.String()
+	result.Name = aws.ToString(bucket.Name)
+	result.Region = bucket.Region
+	result.Tags = bucket.Tags
+	result.CreatedAt = bucket.CreationDate
+	result.Attributes = S3Attributes{
+		BucketName: aws.ToString(bucket.Name),
+	}
+	return result
+}
+
+type dnsSearcher struct{}
+
+func (dnsSearcher) Type() string       { return "dns" }
+func (dnsSearcher) SubTypes() []string { return nil }
+func (dnsSearcher) Global() bool       { return true }
+
+func (dnsSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	// the error is returned as is, it may join the failure of some hosted zones with the truncation of others
+	records, err := services.ListDnsRecords(ctx, cfg, region, maxPages)
+	return toInterfaces(records), err
+}
+
+func (dnsSearcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[services.DnsRecord](data)
+}
+
+func (dnsSearcher) ResourceId(resource interface{}) string {
+	record := resource.(services.DnsRecord)
+	// records of routing policies share their name and type, the set identifier tells them apart
+	return strings.Join([]string{record.HostedZoneName, aws.ToString(record.Record.Name), string(record.Record.Type), aws.ToString(record.Record.SetIdentifier)}, "|")
+}
+
+func (dnsSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchDns(resource.(services.DnsRecord), matcher)
+}
+
+func (dnsSearcher) Fields(resource interface{}) Fields {
+	record := resource.(services.DnsRecord)
+	fields := Fields{}
+	fields.Add(FieldId, dnsSearcher{}.ResourceId(record))
+	fields.Add(FieldName, aws.ToString(record.Record.Name))
+	fields.Add(FieldType, string(record.Record.Type))
+	// the targets of the record, addresses are also matched as networks
+	fields.Add(FieldDns, aws.ToString(record.Record.Name))
+	for _, value := range record.Record.ResourceRecords {
+		fields.Add(FieldDns, aws.ToString(value.Value))
+		fields.Add(FieldIp, aws.ToString(value.Value))
+	}
+	if record.Record.AliasTarget != nil {
+		fields.Add(FieldDns, aws.ToString(record.Record.AliasTarget.DNSName))
+	}
+	return tagFields(fields, record.Tags)
+}
+
+func (dnsSearcher) AttributeTypes() []interface{} { return []interface{}{DnsAttributes{}} }
+
+func (dnsSearcher) Result(resource interface{}, scope Scope) Resource {
+	record := resource.(services.DnsRecord)
+	result := scope.GlobalResource()
+	result.Id = dnsSearcher{}.ResourceId(record)
+	result.Name = aws.ToString(record.Record.Name)
+	result.Tags = record.Tags
+	values := []string{}
+	for _, value := range record.Record.ResourceRecords {
+		values = append(values, aws.ToString(value.Value))
+	}
+	if record.Record.AliasTarget != nil {
+		values = append(values, aws.ToString(record.Record.AliasTarget.DNSName))
+	}
+	result.Attributes = DnsAttributes{
+		HostedZoneName: record.HostedZoneName,
+		DnsRecordName:  aws.ToString(record.Record.Name),
+		DnsRecordType:  string(record.Record.Type),
+		Values:         values,
+	}
+	return result
+}
+
+type iamSearcher struct{}
+
+func (iamSearcher) Type() string       { return "iam" }
+func (iamSearcher) SubTypes() []string { return []string{"user", "key"} }
+func (iamSearcher) Global() bool       { return true }
+
+func (iamSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	switch resourceSubType {
+	case "user":
+		users, err := services.ListIamUsers(ctx, cfg, region, maxPages)
+		return toInterfaces(users), err
+	case "key":
+		accessKeys, err := services.ListIamUserKeys(ctx, cfg, region, maxPages)
+		return toInterfaces(accessKeys), err
+	}
+	return nil, fmt.Errorf("unknown IAM resource subtype %q", resourceSubType)
+}
+
+func (iamSearcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	if resourceSubType == "key" {
+		return decodeResources[iam_types.AccessKeyMetadata](data)
+	}
+	return decodeResources[iam_types.User](data)
+}
+
+// ResourceId identifies users by their user ID, like their results, it doesn't change when a user is renamed.
+func (iamSearcher) ResourceId(resource interface{}) string {
+	switch resource := resource.(type) {
+	case iam_types.User:
+		return aws.ToString(resource.UserId)
+	case iam_types.AccessKeyMetadata:
+		return aws.ToString(resource.AccessKeyId)
+	}
+	return ""
+}
+
+func (iamSearcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	// access keys have no ARN
+	return "user", resourceArn.Service == "iam" && resourceArn.ResourceType == "user"
+}
+
+func (iamSearcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	// the resource of a user ARN is its path followed by its name, e.g. user/division/name
+	path := strings.Split(resourceArn.ResourceId, "/")
+	return services.GetIamUser(ctx, cfg, region, path[len(path)-1])
+}
+
+func (iamSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	switch resource := resource.(type) {
+	case iam_types.User:
+		return services.MatchIamUser(resource, matcher)
+	case iam_types.AccessKeyMetadata:
+		return services.MatchIamUserKey(resource, matcher)
+	}
+	return false
+}
+
+func (iamSearcher) Fields(resource interface{}) Fields {
+	fields := Fields{}
+	switch resource := resource.(type) {
+	case iam_types.User:
+		fields.Add(FieldId, aws.ToString(resource.UserId))
+		fields.Add(FieldName, aws.ToString(resource.UserName))
+		fields.Add(FieldUser, aws.ToString(resource.UserName))
+		fields.Add(FieldArn, aws.ToString(resource.Arn))
+		tagFields(fields, services.IamTags(resource.Tags))
+	case iam_types.AccessKeyMetadata:
+		fields.Add(FieldId, aws.ToString(resource.AccessKeyId))
+		fields.Add(FieldName, aws.ToString(resource.AccessKeyId))
+		fields.Add(FieldUser, aws.ToString(resource.UserName))
+		fields.Add(FieldState, string(resource.Status))
+	}
+	return fields
+}
+
+func (iamSearcher) AttributeTypes() []interface{} {
+	return []interface{}{IamUserAttributes{}, IamUserKeyAttributes{}}
+}
+
+func (iamSearcher) Result(resource interface{}, scope Scope) Resource {
+	result := scope.GlobalResource()
+	switch resource := resource.(type) {
+	case iam_types.User:
+		result.Id = aws.ToString(resource.UserId)
+		result.Arn = aws.ToString(resource.Arn)
+		result.Name = aws.ToString(resource.UserName)
+		result.CreatedAt = resource.CreateDate
+		result.Tags = services.IamTags(resource.Tags)
+		result.Attributes = IamUserAttributes{
+			UserName: aws.ToString(resource.UserName),
+		}
+	case iam_types.AccessKeyMetadata:
+		result.Id = aws.ToString(resource.AccessKeyId)
+		result.Name = aws.ToString(resource.AccessKeyId)
+		result.CreatedAt = resource.CreateDate
+		result.Attributes = iamUserKeyAttributes(resource)
+	case services.IamAccessKey:
+		result.Id = aws.ToString(resource.AccessKeyId)
+		result.Name = aws.ToString(resource.AccessKeyId)
+		result.CreatedAt = resource.CreateDate
+		attributes := iamUserKeyAttributes(resource.AccessKeyMetadata)
+		if resource.LastUsed != nil {
+			attributes.LastUsedDate = resource.LastUsed.LastUsedDate
+			attributes.LastUsedService = notApplicable(aws.ToString(resource.LastUsed.ServiceName))
+			attributes.LastUsedRegion = notApplicable(aws.ToString(resource.LastUsed.Region))
+		}
+		result.Attributes = attributes
+	}
+	return result
+}
+
+func iamUserKeyAttributes(key iam_types.AccessKeyMetadata) IamUserKeyAttributes {
+	return IamUserKeyAttributes{
+		UserName:  aws.ToString(key.UserName),
+		AccessKey: aws.ToString(key.AccessKeyId),
+		Status:    string(key.Status),
+	}
+}
+
+// notApplicable blanks the N/A IAM reports for the service and region of keys that were never used.
+func notApplicable(value string) string {
+	if value == "N/A" {
+		return ""
+	}
+	return value
+}
+
+type elasticIpSearcher struct{}
+
+func (elasticIpSearcher) Type() string       { return "elastic_ip" }
+func (elasticIpSearcher) SubTypes() []string { return nil }
+func (elasticIpSearcher) Global() bool       { return false }
+
+func (elasticIpSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	addresses, err := services.ListElasticIps(ctx, cfg, region)
+	if err != nil {
+		return nil, fmt.Errorf("error finding elastic IP addresses: %w", err)
+	}
+	return toInterfaces(addresses), nil
+}
+
+func (elasticIpSearcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[ec2_types.Address](data)
+}
+
+func (elasticIpSearcher) ResourceId(resource interface{}) string {
+	address := resource.(ec2_types.Address)
+	// EC2-Classic addresses have no allocation ID
+	if address.AllocationId == nil {
+		return aws.ToString(address.PublicIp)
+	}
+	return aws.ToString(address.AllocationId)
+}
+
+func (elasticIpSearcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	return "", resourceArn.Service == "ec2" && resourceArn.ResourceType == "elastic-ip"
+}
+
+func (elasticIpSearcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	return services.GetElasticIp(ctx, cfg, region, resourceArn.ResourceId)
+}
+
+func (elasticIpSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchElasticIp(resource.(ec2_types.Address), matcher)
+}
+
+func (elasticIpSearcher) Fields(resource interface{}) Fields {
+	address := resource.(ec2_types.Address)
+	fields := Fields{}
+	fields.Add(FieldId, aws.ToString(address.AllocationId))
+	fields.Add(FieldIp, aws.ToString(address.PublicIp), aws.ToString(address.PrivateIpAddress))
+	return ec2Fields(fields, address.Tags)
+}
+
+func (elasticIpSearcher) AttributeTypes() []interface{} { return []interface{}{ElasticIpAttributes{}} }
+
+func (elasticIpSearcher) Result(resource interface{}, scope Scope) Resource {
+	address := resource.(ec2_types.Address)
+	result := scope.RegionalResource()
+	result.Id = elasticIpSearcher{}.ResourceId(address)
+	if address.AllocationId != nil {
+		result.Arn = scope.Arn("ec2", "elastic-ip/"+result.Id)
+	}
+	result.Name = nameTag(address.Tags)
+	if result.Name == "" {
+		result.Name = aws.ToString(address.PublicIp)
+	}
+	result.Tags = services.Ec2Tags(address.Tags)
+	result.Attributes = ElasticIpAttributes{
+		PublicIp:      aws.ToString(address.PublicIp),
+		AllocationId:  aws.ToString(address.AllocationId),
+		InstanceId:    aws.ToString(address.InstanceId),
+		PrivateIp:     aws.ToString(address.PrivateIpAddress),
+		AssociationId: aws.ToString(address.AssociationId),
+	}
+	return result
+}
+
+type cloudfrontSearcher struct{}
+
+func (cloudfrontSearcher) Type() string       { return "cloudfront" }
+func (cloudfrontSearcher) SubTypes() []string { return nil }
+func (cloudfrontSearcher) Global() bool       { return true }
+
+func (cloudfrontSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	distributions, err := services.ListCloudfrontDistributions(ctx, cfg, region, maxPages)
+	if err != nil {
+		return toInterfaces(distributions), fmt.Errorf("error finding cloudfront distributions: %w", err)
+	}
+	return toInterfaces(distributions), nil
+}
+
+func (cloudfrontSearcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[services.CloudfrontDistribution](data)
+}
+
+func (cloudfrontSearcher) ResourceId(resource interface{}) string {
+	return aws.ToString(resource.(services.CloudfrontDistribution).Id)
+}
+
+func (cloudfrontSearcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	return "", resourceArn.Service == "cloudfront" && resourceArn.ResourceType == "distribution"
+}
+
+func (cloudfrontSearcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	return services.GetCloudfrontDistribution(ctx, cfg, region, resourceArn.ResourceId)
+}
+
+func (cloudfrontSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchCloudfront(resource.(services.CloudfrontDistribution), matcher)
+}
+
+func (cloudfrontSearcher) Fields(resource interface{}) Fields {
+	distribution := resource.(services.CloudfrontDistribution)
+	fields := Fields{}
+	fields.Add(FieldId, aws.ToString(distribution.Id))
+	fields.Add(FieldArn, aws.ToString(distribution.ARN))
+	fields.Add(FieldState, aws.ToString(distribution.Status))
+	fields.Add(FieldDns, aws.ToString(distribution.DomainName))
+	if distribution.Aliases != nil {
+		fields.Add(FieldDns, distribution.Aliases.Items...)
+	}
+	return tagFields(fields, distribution.Tags)
+}
+
+func (cloudfrontSearcher) AttributeTypes() []interface{} {
+	return []interface{}{CloudfrontAttributes{}}
+}
+
+func (cloudfrontSearcher) Result(resource interface{}, scope Scope) Resource {
+	distribution := resource.(services.CloudfrontDistribution)
+	result := scope.GlobalResource()
+	result.Id = aws.ToString(distribution.Id)
+	result.Arn = aws.ToString(distribution.ARN)
+	result.Name = aws.ToString(distribution.DomainName)
+	result.Tags = distribution.Tags
+	aliases := []string{}
+	if distribution.Aliases != nil {
+		aliases = append(aliases, distribution.Aliases.Items...)
+	}
+	result.Attributes = CloudfrontAttributes{
+		DistributionId: aws.ToString(distribution.Id),
+		DomainName:     aws.ToString(distribution.DomainName),
+		Aliases:        aliases,
+		Status:         aws.ToString(distribution.Status),
+	}
+	return result
+}
+
+type ipSearcher struct{}
+
+func (ipSearcher) Type() string       { return "ip" }
+func (ipSearcher) SubTypes() []string { return nil }
+func (ipSearcher) Global() bool       { return false }
+
+func (ipSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	networkInterfaces, err := services.ListNetworkInterfaces(ctx, cfg, region, maxPages)
+	if err != nil {
+		return toInterfaces(networkInterfaces), fmt.Errorf("error finding network interfaces: %w", err)
+	}
+	return toInterfaces(networkInterfaces), nil
+}
+
+func (ipSearcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[ec2_types.NetworkInterface](data)
+}
+
+func (ipSearcher) ResourceId(resource interface{}) string {
+	return aws.ToString(resource.(ec2_types.NetworkInterface).NetworkInterfaceId)
+}
+
+func (ipSearcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	return "", resourceArn.Service == "ec2" && resourceArn.ResourceType == "network-interface"
+}
+
+func (ipSearcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	return services.GetNetworkInterface(ctx, cfg, region, resourceArn.ResourceId)
+}
+
+func (ipSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchNetworkInterface(resource.(ec2_types.NetworkInterface), matcher)
+}
+
+func (ipSearcher) Fields(resource interface{}) Fields {
+	eni := resource.(ec2_types.NetworkInterface)
+	fields := Fields{}
+	fields.Add(FieldId, aws.ToString(eni.NetworkInterfaceId))
+	fields.Add(FieldState, string(eni.Status))
+	fields.Add(FieldType, string(eni.InterfaceType))
+	privateIps, publicIps, ipv6s := services.NetworkInterfaceAddresses(eni)
+	fields.Add(FieldIp, privateIps...)
+	fields.Add(FieldIp, publicIps...)
+	fields.Add(FieldIp, ipv6s...)
+	fields.Add(FieldDns, aws.ToString(eni.PrivateDnsName))
+	fields.Add(FieldVpc, aws.ToString(eni.VpcId))
+	fields.Add(FieldSubnet, aws.ToString(eni.SubnetId))
+	return ec2Fields(fields, eni.TagSet)
+}
+
+func (ipSearcher) AttributeTypes() []interface{} { return []interface{}{IpAttributes{}} }
+
+func (ipSearcher) Result(resource interface{}, scope Scope) Resource {
+	eni := resource.(ec2_types.NetworkInterface)
+	privateIps, publicIps, ipv6s := services.NetworkInterfaceAddresses(eni)
+
+	result := scope.RegionalResource()
+	result.Id = aws.ToString(eni.NetworkInterfaceId)
+	result.Arn = scope.Arn("ec2", "network-interface/"+result.Id)
+	result.Name = nameTag(eni.TagSet)
+	if result.Name == "" {
+		result.Name = aws.ToString(eni.Description)
+	}
+	result.Tags = services.Ec2Tags(eni.TagSet)
+
+	// the address lists are empty rather than null when the interface has no such address
+	attributes := IpAttributes{
+		NetworkInterfaceId: aws.ToString(eni.NetworkInterfaceId),
+		Service:            services.NetworkInterfaceService(eni),
+		InterfaceType:      string(eni.InterfaceType),
+		RequesterId:        aws.ToString(eni.RequesterId),
+		RequesterManaged:   aws.ToBool(eni.RequesterManaged),
+		Description:        aws.ToString(eni.Description),
+		PrivateIpAddresses: append([]string{}, privateIps...),
+		PublicIpAddresses:  append([]string{}, publicIps...),
+		Ipv6Addresses:      append([]string{}, ipv6s...),
+		VpcId:              aws.ToString(eni.VpcId),
+		SubnetId:           aws.ToString(eni.SubnetId),
+		Status:             string(eni.Status),
+	}
+	if eni.Attachment != nil {
+		attributes.InstanceId = aws.ToString(eni.Attachment.InstanceId)
+	}
+	result.Attributes = attributes
+	return result
+}
+
+type tagsSearcher struct{}
+
+func (tagsSearcher) Type() string       { return "tags" }
+func (tagsSearcher) SubTypes() []string { return nil }
+func (tagsSearcher) Global() bool       { return false }
+
+// Explicit keeps the tags searcher out of the searches of every type, the tagged resources of the other types
+// would be found twice.
+func (tagsSearcher) Explicit() bool { return true }
+
+func (tagsSearcher) Fetch(ctx context.Context, cfg aws.Config, region string, resourceSubType string, maxPages int) ([]interface{}, error) {
+	resources, err := services.ListTaggedResources(ctx, cfg, region, maxPages)
+	if err != nil {
+		return toInterfaces(resources), fmt.Errorf("error finding tagged resources: %w", err)
+	}
+	return toInterfaces(resources), nil
+}
+
+func (tagsSearcher) Decode(resourceSubType string, data []byte) ([]interface{}, error) {
+	return decodeResources[services.TaggedResource](data)
+}
+
+func (tagsSearcher) ResourceId(resource interface{}) string {
+	return resource.(services.TaggedResource).Arn
+}
+
+// DescribesArn accepts every ARN, the tags searcher is the fallback of the resource types no other searcher describes.
+func (tagsSearcher) DescribesArn(resourceArn services.ResourceArn) (string, bool) {
+	return "", true
+}
+
+func (tagsSearcher) DescribeArn(ctx context.Context, cfg aws.Config, region string, resourceArn services.ResourceArn) (interface{}, error) {
+	return services.GetTaggedResource(ctx, cfg, region, resourceArn.Arn)
+}
+
+func (tagsSearcher) Match(resource interface{}, matcher services.Matcher) bool {
+	return services.MatchTaggedResource(resource.(services.TaggedResource), matcher)
+}
+
+func (tagsSearcher) Fields(resource interface{}) Fields {
+	tagged := resource.(services.TaggedResource)
+	fields := Fields{}
+	fields.Add(FieldArn, tagged.Arn)
+	fields.Add(FieldName, tagged.Tags["Name"])
+	if resourceArn, err := services.ParseResourceArn(tagged.Arn); err == nil {
+		fields.Add(FieldId, resourceArn.ResourceId)
+		fields.Add(FieldType, taggedResourceType(resourceArn), resourceArn.ResourceType)
+	}
+	return tagFields(fields, tagged.Tags)
+}
+
+func (tagsSearcher) AttributeTypes() []interface{} { return []interface{}{TaggedResourceAttributes{}} }
+
+func (tagsSearcher) Result(resource interface{}, scope Scope) Resource {
+	tagged := resource.(services.TaggedResource)
+	result := scope.RegionalResource()
+	result.Arn = tagged.Arn
+	result.Id = tagged.Arn
+	result.Name = tagged.Tags["Name"]
+	result.Tags = tagged.Tags
+
+	attributes := TaggedResourceAttributes{}
+	if resourceArn, err := services.ParseResourceArn(tagged.Arn); err == nil {
+		result.Id = resourceArn.ResourceId
+		attributes = TaggedResourceAttributes{
+			Service:    resourceArn.Service,
+			Type:       resourceArn.ResourceType,
+			ResourceId: resourceArn.ResourceId,
+		}
+	}
+	if result.Name == "" {
+		result.Name = result.Id
+	}
+	result.Attributes = attributes
+	return result
+}
+
+// taggedResourceType names the type of a resource the way the Resource Groups Tagging API does, e.g. ec2:instance.
+func taggedResourceType(resourceArn services.ResourceArn) string {
+	if resourceArn.ResourceType == "" {
+		return resourceArn.Service
+	}
+	return resourceArn.Service + ":" + resourceArn.ResourceType
+}
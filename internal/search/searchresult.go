@@ -1,69 +1,121 @@
 package search
 
-type SearchResult struct {
-	Account string `json:"account"`
-	Profile string `json:"profile"`
-}
+import "time"
 
-type SearchResultNonGlobal struct {
-	SearchResult
-	Region string `json:"region"`
+// Resource is the envelope of every search result, whatever its type: the fields shared by all the types,
+// with the fields specific to the type under Attributes.
+type Resource struct {
+	ResourceType    string            `json:"resource_type"`
+	ResourceSubType string            `json:"resource_subtype,omitempty"`
+	Arn             string            `json:"arn,omitempty"`
+	Id              string            `json:"id"`
+	Name            string            `json:"name,omitempty"`
+	AccountId       string            `json:"account_id"`
+	AccountAlias    string            `json:"account_alias,omitempty"`
+	AccountName     string            `json:"account_name,omitempty"`
+	OuPath          string            `json:"ou_path,omitempty"`
+	Profile         string            `json:"profile"`
+	Profiles        []string          `json:"profiles,omitempty"`
+	Region          string            `json:"region,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	CreatedAt       *time.Time        `json:"created_at,omitempty"`
+	// MatchedFields are the fields (see QueryFields) the search term matched
+	MatchedFields []string `json:"matched_fields,omitempty"`
+	// Attributes are specific to the resource type, e.g. Ec2Attributes
+	Attributes interface{} `json:"attributes"`
+	// Raw is the resource as returned by AWS, only when requested
+	Raw interface{} `json:"raw,omitempty"`
 }
 
-type VpcSearchResult struct {
-	SearchResultNonGlobal
-	VpcName   string `json:"vpc_name"`
+type VpcAttributes struct {
 	VpcId     string `json:"vpc_id"`
 	CidrBlock string `json:"cidr_block"`
+	State     string `json:"state"`
+}
+
+type SubnetAttributes struct {
+	SubnetId         string   `json:"subnet_id"`
+	VpcId            string   `json:"vpc_id"`
+	CidrBlocks       []string `json:"cidr_blocks"`
+	AvailabilityZone string   `json:"availability_zone"`
 }
 
-type Ec2SearchResult struct {
-	SearchResultNonGlobal
+type Ec2Attributes struct {
 	InstanceId       string `json:"instance_id"`
-	InstanceName     string `json:"instance_name"`
+	InstanceType     string `json:"instance_type"`
+	State            string `json:"state"`
 	PrivateIpAddress string `json:"private_ip_address"`
 	PrivateDnsName   string `json:"private_dns_name"`
 	PublicDnsName    string `json:"public_dns_name"`
 	PublicIpAddress  string `json:"public_ip_address"`
+	VpcId            string `json:"vpc_id"`
+	SubnetId         string `json:"subnet_id"`
 }
 
-type LoadBalancerSearchResult struct {
-	SearchResultNonGlobal
+type LoadBalancerAttributes struct {
 	LoadBalancerName    string `json:"load_balancer_name"`
 	LoadBalancerDnsName string `json:"load_balancer_dns_name"`
 }
 
-type S3SearchResult struct {
-	SearchResult
+type S3Attributes struct {
 	BucketName string `json:"bucket_name"`
 }
 
-type DNSSearchResult struct {
-	SearchResult
-	HostedZoneName string `json:"hosted_zone_name"`
-	DnsRecordName  string `json:"dns_record_name"`
-	DnsRecordType  string `json:"dns_record_type"`
+type DnsAttributes struct {
+	HostedZoneName string   `json:"hosted_zone_name"`
+	DnsRecordName  string   `json:"dns_record_name"`
+	DnsRecordType  string   `json:"dns_record_type"`
+	Values         []string `json:"values"`
 }
 
-type IamUserSearchResult struct {
-	SearchResult
+type IamUserAttributes struct {
 	UserName string `json:"user_name"`
 }
 
-type IamUserKeySearchResult struct {
-	IamUserSearchResult
+type IamUserKeyAttributes struct {
+	UserName  string `json:"user_name"`
 	AccessKey string `json:"access_key"`
+	Status    string `json:"status"`
+	// the last use of the key is only known when the key was looked up by its ID (see FindAccessKey)
+	LastUsedDate    *time.Time `json:"last_used_date,omitempty"`
+	LastUsedService string     `json:"last_used_service,omitempty"`
+	LastUsedRegion  string     `json:"last_used_region,omitempty"`
+}
+
+type ElasticIpAttributes struct {
+	PublicIp      string `json:"public_ip"`
+	AllocationId  string `json:"allocation_id"`
+	InstanceId    string `json:"instance_id"`
+	PrivateIp     string `json:"private_ip"`
+	AssociationId string `json:"association_id"`
+}
+
+type CloudfrontAttributes struct {
+	DistributionId string   `json:"distribution_id"`
+	DomainName     string   `json:"domain_name"`
+	Aliases        []string `json:"aliases"`
+	Status         string   `json:"status"`
 }
 
-type ElasticIpSearchResult struct {
-	SearchResultNonGlobal
-	PublicIp   string `json:"public_ip"`
-	InstanceId string `json:"instance_id"`
+type IpAttributes struct {
+	NetworkInterfaceId string   `json:"network_interface_id"`
+	Service            string   `json:"service"`
+	InterfaceType      string   `json:"interface_type"`
+	RequesterId        string   `json:"requester_id"`
+	RequesterManaged   bool     `json:"requester_managed"`
+	Description        string   `json:"description"`
+	InstanceId         string   `json:"instance_id"`
+	PrivateIpAddresses []string `json:"private_ip_addresses"`
+	PublicIpAddresses  []string `json:"public_ip_addresses"`
+	Ipv6Addresses      []string `json:"ipv6_addresses"`
+	VpcId              string   `json:"vpc_id"`
+	SubnetId           string   `json:"subnet_id"`
+	Status             string   `json:"status"`
 }
 
-type CloudfrontSearchResult struct {
-	SearchResult
-	DistributionArn string `json:"distribution_arn"`
-	DistributionId  string `json:"distribution_id"`
-	DomainName      string `json:"domain_name"`
+// TaggedResourceAttributes are the parts of the ARN of a tagged resource, e.g. ec2, instance and i-0abc.
+type TaggedResourceAttributes struct {
+	Service    string `json:"service"`
+	Type       string `json:"type"`
+	ResourceId string `json:"resource_id"`
 }
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/smithy-go"
+)
+
+// ErrNotFound is returned when a described resource doesn't exist.
+var ErrNotFound = errors.New("resource not found")
+
+// notFoundError wraps the errors AWS returns for missing resources (e.g. InvalidInstanceID.NotFound,
+// NoSuchEntity) with ErrNotFound, other errors are returned as is.
+func notFoundError(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		if strings.HasSuffix(code, "NotFound") || strings.HasPrefix(code, "NoSuch") {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+	}
+	return err
+}
+
+// ResourceArn is an ARN split into the parts that identify a resource.
+type ResourceArn struct {
+	// Arn is the whole ARN
+	Arn       string `json:"arn"`
+	Partition string `json:"partition"`
+	Service   string `json:"service"`
+	Region    string `json:"region"`
+	AccountId string `json:"account_id"`
+	// ResourceType is the type of the resource within the service, e.g. instance for ec2, empty when the
+	// ARN has none
+	ResourceType string `json:"resource_type"`
+	ResourceId   string `json:"resource_id"`
+}
+
+// ParseResourceArn parses an ARN. The resource part is either type/id, type:id or a bare id, such as the
+// name of an S3 bucket.
+func ParseResourceArn(value string) (ResourceArn, error) {
+	parsed, err := arn.Parse(value)
+	if err != nil {
+		return ResourceArn{}, fmt.Errorf("invalid ARN %q: %w", value, err)
+	}
+
+	resourceArn := ResourceArn{
+		Arn:        value,
+		Partition:  parsed.Partition,
+		Service:    parsed.Service,
+		Region:     parsed.Region,
+		AccountId:  parsed.AccountID,
+		ResourceId: parsed.Resource,
+	}
+	if i := strings.IndexAny(parsed.Resource, "/:"); i >= 0 {
+		resourceArn.ResourceType, resourceArn.ResourceId = parsed.Resource[:i], parsed.Resource[i+1:]
+	} else if parsed.Service == "s3" {
+		resourceArn.ResourceType = "bucket"
+	}
+	return resourceArn, nil
+}
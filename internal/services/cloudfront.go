@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"log"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/transport/http"
@@ -12,28 +12,99 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
 )
 
-func FindCloudfront(config aws.Config, region string, searchValue string) ([]types.DistributionSummary, error) {
+// CloudfrontDistribution is a distribution together with its tags.
+type CloudfrontDistribution struct {
+	types.DistributionSummary
+	Tags map[string]string
+}
+
+func ListCloudfrontDistributions(ctx context.Context, config aws.Config, region string, maxPages int) ([]CloudfrontDistribution, error) {
 	config.Region = region
 
 	cfClient := cloudfront.NewFromConfig(config)
-	output, err := cfClient.ListDistributions(context.TODO(), &cloudfront.ListDistributionsInput{})
-	if err != nil {
-		var accessDeniedErr *http.ResponseError
-		if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
+	summaries := []types.DistributionSummary{}
+	var truncatedErr error
+
+	paginator := cloudfront.NewListDistributionsPaginator(cfClient, &cloudfront.ListDistributionsInput{})
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			truncatedErr = truncatedError("ListDistributions", maxPages)
+			break
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			var accessDeniedErr *http.ResponseError
+			if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
+				return nil, err
+			}
+			log.Printf("Unable to list cloudfront distributions %v", err)
 			return nil, err
 		}
-		fmt.Printf("Unable to list cloudfront distributions %v", err)
-		return nil, err
-	}
 
-	filteredCfDistributions := []types.DistributionSummary{}
-	if output != nil {
-		for _, distribution := range output.DistributionList.Items {
-			if strings.Contains(*distribution.DomainName, searchValue) || strings.Contains(*distribution.Id, searchValue) {
-				filteredCfDistributions = append(filteredCfDistributions, distribution)
-			}
+		if page.DistributionList != nil {
+			summaries = append(summaries, page.DistributionList.Items...)
 		}
 	}
 
-	return filteredCfDistributions, err
+	distributions := make([]CloudfrontDistribution, len(summaries))
+	forEachConcurrently(len(summaries), func(i int) {
+		distributions[i] = CloudfrontDistribution{DistributionSummary: summaries[i]}
+		distributions[i].Tags = cloudfrontTags(ctx, cfClient, aws.ToString(summaries[i].ARN))
+	})
+	return distributions, truncatedErr
+}
+
+// GetCloudfrontDistribution describes a single distribution, the error wraps ErrNotFound when it doesn't exist.
+func GetCloudfrontDistribution(ctx context.Context, config aws.Config, region string, distributionId string) (CloudfrontDistribution, error) {
+	config.Region = region
+
+	cfClient := cloudfront.NewFromConfig(config)
+	output, err := cfClient.GetDistribution(ctx, &cloudfront.GetDistributionInput{Id: aws.String(distributionId)})
+	if err != nil {
+		return CloudfrontDistribution{}, notFoundError(err)
+	}
+	if output.Distribution == nil {
+		return CloudfrontDistribution{}, fmt.Errorf("distribution %s: %w", distributionId, ErrNotFound)
+	}
+
+	// the summary holds the fields of the distribution that its listing returns
+	distribution := output.Distribution
+	summary := types.DistributionSummary{
+		Id:               distribution.Id,
+		ARN:              distribution.ARN,
+		DomainName:       distribution.DomainName,
+		Status:           distribution.Status,
+		LastModifiedTime: distribution.LastModifiedTime,
+	}
+	if distribution.DistributionConfig != nil {
+		summary.Aliases = distribution.DistributionConfig.Aliases
+		summary.Comment = distribution.DistributionConfig.Comment
+		summary.Enabled = distribution.DistributionConfig.Enabled
+	}
+	return CloudfrontDistribution{
+		DistributionSummary: summary,
+		Tags:                cloudfrontTags(ctx, cfClient, aws.ToString(distribution.ARN)),
+	}, nil
+}
+
+// cloudfrontTags returns the tags of a distribution, nil when it has none or they can't be read.
+func cloudfrontTags(ctx context.Context, cfClient *cloudfront.Client, arn string) map[string]string {
+	output, err := cfClient.ListTagsForResource(ctx, &cloudfront.ListTagsForResourceInput{Resource: aws.String(arn)})
+	if err != nil {
+		log.Printf("Unable to list the tags of cloudfront distribution %s, %v", arn, err)
+		return nil
+	}
+	if output.Tags == nil || len(output.Tags.Items) == 0 {
+		return nil
+	}
+
+	tags := map[string]string{}
+	for _, tag := range output.Tags.Items {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+func MatchCloudfront(distribution CloudfrontDistribution, matcher Matcher) bool {
+	return matcher.Match(aws.ToString(distribution.DomainName), aws.ToString(distribution.Id)) || matcher.MatchTags(distribution.Tags)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -12,39 +13,111 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
 )
 
-func FindDns(config aws.Config, region string, searchValue string) map[string][]types.ResourceRecordSet {
+// DnsRecord is a record set together with the hosted zone it belongs to, and the tags of the zone.
+type DnsRecord struct {
+	HostedZoneName string
+	HostedZoneId   string
+	Record         types.ResourceRecordSet
+	Tags           map[string]string
+}
+
+// listTagsForResourcesBatch is the most hosted zones ListTagsForResources takes per call.
+const listTagsForResourcesBatch = 10
+
+func ListDnsRecords(ctx context.Context, config aws.Config, region string, maxPages int) ([]DnsRecord, error) {
 
 	config.Region = region
 
 	route53Client := route53.NewFromConfig(config)
-	hostedZones, err := route53Client.ListHostedZones(context.TODO(), &route53.ListHostedZonesInput{})
-	if err != nil {
-		var accessDeniedErr *http.ResponseError
-		if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
-			return nil
+	hostedZones := []types.HostedZone{}
+	var truncatedErr error
+
+	paginator := route53.NewListHostedZonesPaginator(route53Client, &route53.ListHostedZonesInput{})
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			truncatedErr = truncatedError("ListHostedZones", maxPages)
+			break
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			var accessDeniedErr *http.ResponseError
+			if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
+				return nil, err
+			}
+			log.Printf("Unable to list hosted zones, %v", err)
+			return nil, err
 		}
-		fmt.Printf("Unable to list hosted zones, %v", err)
-		return nil
+		hostedZones = append(hostedZones, page.HostedZones...)
 	}
 
-	searchValue = strings.ToLower(searchValue)
+	zoneTags := listHostedZoneTags(ctx, route53Client, hostedZones)
 
-	filteredRecordsMap := make(map[string][]types.ResourceRecordSet)
-	for _, zone := range hostedZones.HostedZones {
-		recordSets, err := route53Client.ListResourceRecordSets(context.TODO(), &route53.ListResourceRecordSetsInput{
+	// a zone whose records can't be listed doesn't stop the others, its failure is returned with the listed records
+	errs := []error{truncatedErr}
+	records := []DnsRecord{}
+	for _, zone := range hostedZones {
+		zoneId := strings.TrimPrefix(aws.ToString(zone.Id), "/hostedzone/")
+		// ListResourceRecordSets has no paginator, the next page starts at the next record name, type and identifier
+		input := &route53.ListResourceRecordSetsInput{
 			HostedZoneId: zone.Id,
-		})
-		if err != nil {
-			fmt.Println("Error listing resource record sets:", err)
-			continue
 		}
+		for pages := 0; ; pages++ {
+			if pageLimitReached(pages, maxPages) {
+				errs = append(errs, truncatedError("ListResourceRecordSets for zone "+*zone.Name, maxPages))
+				break
+			}
+			recordSets, err := route53Client.ListResourceRecordSets(ctx, input)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to list the records of hosted zone %s: %w", *zone.Name, err))
+				break
+			}
 
-		for _, record := range recordSets.ResourceRecordSets {
-			if strings.Contains(aws.ToString(record.Name), searchValue) {
-				filteredRecordsMap[*zone.Name] = append(filteredRecordsMap[*zone.Name], record)
+			for _, record := range recordSets.ResourceRecordSets {
+				records = append(records, DnsRecord{HostedZoneName: *zone.Name, HostedZoneId: zoneId, Record: record, Tags: zoneTags[zoneId]})
 			}
 
+			if !recordSets.IsTruncated {
+				break
+			}
+			input.StartRecordName = recordSets.NextRecordName
+			input.StartRecordType = recordSets.NextRecordType
+			input.StartRecordIdentifier = recordSets.NextRecordIdentifier
 		}
 	}
-	return filteredRecordsMap
+	return records, errors.Join(errs...)
+}
+
+// listHostedZoneTags returns the tags of the hosted zones by zone ID, zones whose tags can't be read have none.
+func listHostedZoneTags(ctx context.Context, route53Client *route53.Client, hostedZones []types.HostedZone) map[string]map[string]string {
+	zoneTags := map[string]map[string]string{}
+	for _, batch := range batches(len(hostedZones), listTagsForResourcesBatch) {
+		zoneIds := []string{}
+		for _, zone := range hostedZones[batch[0]:batch[1]] {
+			zoneIds = append(zoneIds, strings.TrimPrefix(aws.ToString(zone.Id), "/hostedzone/"))
+		}
+
+		output, err := route53Client.ListTagsForResources(ctx, &route53.ListTagsForResourcesInput{
+			ResourceIds:  zoneIds,
+			ResourceType: types.TagResourceTypeHostedzone,
+		})
+		if err != nil {
+			log.Printf("Unable to list the tags of hosted zones, %v", err)
+			return zoneTags
+		}
+		for _, tagSet := range output.ResourceTagSets {
+			if len(tagSet.Tags) == 0 {
+				continue
+			}
+			tags := map[string]string{}
+			for _, tag := range tagSet.Tags {
+				tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+			zoneTags[aws.ToString(tagSet.ResourceId)] = tags
+		}
+	}
+	return zoneTags
+}
+
+func MatchDns(record DnsRecord, matcher Matcher) bool {
+	return matcher.Match(aws.ToString(record.Record.Name)) || matcher.MatchTags(record.Tags)
 }
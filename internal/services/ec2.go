@@ -2,49 +2,95 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-func FindEc2(config aws.Config, region string, searchValue string) ([]types.Instance, error) {
+func ListEc2Instances(ctx context.Context, config aws.Config, region string, maxPages int) ([]types.Instance, error) {
 	config.Region = region
 
 	ec2Client := ec2.NewFromConfig(config)
 	input := &ec2.DescribeInstancesInput{}
+	instances := []types.Instance{}
 
-	output, err := ec2Client.DescribeInstances(context.TODO(), input)
+	paginator := ec2.NewDescribeInstancesPaginator(ec2Client, input)
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			return instances, truncatedError("DescribeInstances", maxPages)
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("Unable to list instances, %v", err)
+			return instances, err
+		}
+
+		for _, reservation := range page.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+	}
 
+	return instances, nil
+}
+
+// GetEc2Instance describes a single instance, the error wraps ErrNotFound when it doesn't exist.
+func GetEc2Instance(ctx context.Context, config aws.Config, region string, instanceId string) (types.Instance, error) {
+	config.Region = region
+
+	ec2Client := ec2.NewFromConfig(config)
+	output, err := ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceId}})
 	if err != nil {
-		log.Printf("Unable to list instances, %v", err)
-		return nil, err
+		return types.Instance{}, notFoundError(err)
 	}
+	for _, reservation := range output.Reservations {
+		if len(reservation.Instances) > 0 {
+			return reservation.Instances[0], nil
+		}
+	}
+	return types.Instance{}, fmt.Errorf("instance %s: %w", instanceId, ErrNotFound)
+}
 
-	searchValue = strings.ToLower(searchValue)
-
-	filteredInstances := []types.Instance{}
-	if output != nil {
-		for _, reservation := range output.Reservations {
-			for _, instance := range reservation.Instances {
-				if instance.PrivateDnsName != nil && strings.Contains(strings.ToLower(*instance.PrivateDnsName), searchValue) ||
-					instance.PrivateIpAddress != nil && strings.Contains(*instance.PrivateIpAddress, searchValue) ||
-					instance.PublicDnsName != nil && strings.Contains(strings.ToLower(*instance.PublicDnsName), searchValue) ||
-					instance.PublicIpAddress != nil && strings.Contains(*instance.PublicIpAddress, searchValue) ||
-					instance.InstanceId != nil && strings.Contains(strings.ToLower(*instance.InstanceId), searchValue) {
-					filteredInstances = append(filteredInstances, instance)
-				}
-				for _, tag := range instance.Tags {
-					if tag.Key != nil && strings.Contains(strings.ToLower(*tag.Key), searchValue) || tag.Value != nil && strings.Contains(strings.ToLower(*tag.Value), searchValue) {
-						filteredInstances = append(filteredInstances, instance)
-						break
-					}
-				}
+// Ec2InstanceAddresses returns every private, public and IPv6 address of an instance, across all its network interfaces.
+func Ec2InstanceAddresses(instance types.Instance) []string {
+	addresses := []string{}
+	if instance.PrivateIpAddress != nil {
+		addresses = append(addresses, *instance.PrivateIpAddress)
+	}
+	if instance.PublicIpAddress != nil {
+		addresses = append(addresses, *instance.PublicIpAddress)
+	}
+	for _, eni := range instance.NetworkInterfaces {
+		for _, privateIp := range eni.PrivateIpAddresses {
+			if privateIp.PrivateIpAddress != nil {
+				addresses = append(addresses, *privateIp.PrivateIpAddress)
+			}
+			if privateIp.Association != nil && privateIp.Association.PublicIp != nil {
+				addresses = append(addresses, *privateIp.Association.PublicIp)
 			}
 		}
+		for _, ipv6 := range eni.Ipv6Addresses {
+			if ipv6.Ipv6Address != nil {
+				addresses = append(addresses, *ipv6.Ipv6Address)
+			}
+		}
+	}
+	return addresses
+}
+
+func MatchEc2(instance types.Instance, matcher Matcher) bool {
+	// an IP address finds the instance that has it, a CIDR block every instance inside it
+	if matched, ok := matcher.MatchNetwork(Ec2InstanceAddresses(instance), nil); ok {
+		return matched
 	}
 
-	return filteredInstances, nil
+	return matcher.Match(
+		aws.ToString(instance.PrivateDnsName),
+		aws.ToString(instance.PrivateIpAddress),
+		aws.ToString(instance.PublicDnsName),
+		aws.ToString(instance.PublicIpAddress),
+		aws.ToString(instance.InstanceId),
+	) || matcher.MatchTags(Ec2Tags(instance.Tags))
 }
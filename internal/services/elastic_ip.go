@@ -3,33 +3,46 @@ package services
 import (
 	"context"
 	"fmt"
-	"strings"
+	"log"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-func FindElasticIp(config aws.Config, region string, searchValue string) ([]types.Address, error) {
+func ListElasticIps(ctx context.Context, config aws.Config, region string) ([]types.Address, error) {
 	config.Region = region
 
 	ec2Client := ec2.NewFromConfig(config)
 	input := &ec2.DescribeAddressesInput{}
-	output, err := ec2Client.DescribeAddresses(context.TODO(), input)
+	output, err := ec2Client.DescribeAddresses(ctx, input)
 
 	if err != nil {
-		fmt.Printf("Unable to list elastic IPs, %v", err)
+		log.Printf("Unable to list elastic IPs, %v", err)
+		return nil, err
 	}
 
-	filteredElasticIps := []types.Address{}
-	if output != nil {
-		for _, address := range output.Addresses {
-			if strings.Contains(*address.PublicIp, searchValue) {
-				filteredElasticIps = append(filteredElasticIps, address)
-				// fmt.Println(*address.PublicIp)
-			}
-		}
+	return output.Addresses, nil
+}
+
+// GetElasticIp describes a single Elastic IP by its allocation ID, the error wraps ErrNotFound when it doesn't exist.
+func GetElasticIp(ctx context.Context, config aws.Config, region string, allocationId string) (types.Address, error) {
+	config.Region = region
+
+	ec2Client := ec2.NewFromConfig(config)
+	output, err := ec2Client.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{AllocationIds: []string{allocationId}})
+	if err != nil {
+		return types.Address{}, notFoundError(err)
+	}
+	if len(output.Addresses) == 0 {
+		return types.Address{}, fmt.Errorf("elastic IP %s: %w", allocationId, ErrNotFound)
 	}
+	return output.Addresses[0], nil
+}
 
-	return filteredElasticIps, err
+func MatchElasticIp(address types.Address, matcher Matcher) bool {
+	if matched, ok := matcher.MatchNetwork([]string{aws.ToString(address.PublicIp), aws.ToString(address.PrivateIpAddress)}, nil); ok {
+		return matched
+	}
+	return matcher.Match(aws.ToString(address.PublicIp), aws.ToString(address.AllocationId)) || matcher.MatchTags(Ec2Tags(address.Tags))
 }
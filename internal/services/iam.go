@@ -2,58 +2,192 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"log"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
-func FindIamUser(config aws.Config, region string, searchValue string) ([]string, error) {
+func ListIamUsers(ctx context.Context, config aws.Config, region string, maxPages int) ([]types.User, error) {
 
 	config.Region = region
 	iamClient := iam.NewFromConfig(config)
 
-	users, err := iamClient.ListUsers(context.TODO(), &iam.ListUsersInput{})
+	users, err := listIamUsers(ctx, iamClient, maxPages)
+	// ListUsers leaves the tags out, they are listed user by user
+	forEachConcurrently(len(users), func(i int) {
+		users[i].Tags = listIamUserTags(ctx, iamClient, aws.ToString(users[i].UserName))
+	})
+	return users, err
+}
+
+// GetIamUser describes a single user with its tags, the error wraps ErrNotFound when it doesn't exist.
+func GetIamUser(ctx context.Context, config aws.Config, region string, userName string) (types.User, error) {
+	config.Region = region
+	iamClient := iam.NewFromConfig(config)
+
+	output, err := iamClient.GetUser(ctx, &iam.GetUserInput{UserName: aws.String(userName)})
+	if err != nil {
+		return types.User{}, notFoundError(err)
+	}
+	if output.User == nil {
+		return types.User{}, fmt.Errorf("IAM user %s: %w", userName, ErrNotFound)
+	}
+	return *output.User, nil
+}
+
+// listIamUserTags returns the tags of a user, none when they can't be listed.
+func listIamUserTags(ctx context.Context, iamClient *iam.Client, userName string) []types.Tag {
+	output, err := iamClient.ListUserTags(ctx, &iam.ListUserTagsInput{UserName: aws.String(userName)})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list IAM users: %v", err)
+		log.Printf("Unable to list the tags of IAM user %s, %v", userName, err)
+		return nil
+	}
+	return output.Tags
+}
+
+// IamTags returns IAM tags by key, nil when there are none.
+func IamTags(tags []types.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	tagMap := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagMap[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
 	}
-	filteredUsers := []string{}
-	for _, user := range users.Users {
-		if user.UserName != nil && strings.Contains(strings.ToLower(*user.UserName), searchValue) {
-			filteredUsers = append(filteredUsers, *user.UserName)
+	return tagMap
+}
+
+func listIamUsers(ctx context.Context, iamClient *iam.Client, maxPages int) ([]types.User, error) {
+	users := []types.User{}
+
+	paginator := iam.NewListUsersPaginator(iamClient, &iam.ListUsersInput{})
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			return users, truncatedError("ListUsers", maxPages)
 		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list IAM users: %w", err)
+		}
+		users = append(users, page.Users...)
 	}
 
-	return filteredUsers, nil
+	return users, nil
+}
+
+func MatchIamUser(user types.User, matcher Matcher) bool {
+	return matcher.Match(aws.ToString(user.UserName)) || matcher.MatchTags(IamTags(user.Tags))
 }
 
-func FindIamUserKey(config aws.Config, region string, searchValue string) (map[string]string, error) {
+func ListIamUserKeys(ctx context.Context, config aws.Config, region string, maxPages int) ([]types.AccessKeyMetadata, error) {
 
 	config.Region = region
 	iamClient := iam.NewFromConfig(config)
 
-	users, err := iamClient.ListUsers(context.TODO(), &iam.ListUsersInput{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list IAM users: %v", err)
+	users, err := listIamUsers(ctx, iamClient, maxPages)
+	if err != nil && !errors.Is(err, ErrTruncated) {
+		return nil, err
 	}
+	// a truncated user listing still lets the keys of the listed users be searched
+	truncatedErr := err
 
-	filteredAccessKeys := make(map[string]string)
+	keys := []types.AccessKeyMetadata{}
 
-	for _, user := range users.Users {
-		accessKeys, err := iamClient.ListAccessKeys(context.TODO(), &iam.ListAccessKeysInput{
+	for _, user := range users {
+		paginator := iam.NewListAccessKeysPaginator(iamClient, &iam.ListAccessKeysInput{
 			UserName: user.UserName,
 		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to list access keys for user %s: %v", *user.UserName, err)
+		for pages := 0; paginator.HasMorePages(); pages++ {
+			if pageLimitReached(pages, maxPages) {
+				truncatedErr = errors.Join(truncatedErr, truncatedError("ListAccessKeys for user "+*user.UserName, maxPages))
+				break
+			}
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list access keys for user %s: %w", *user.UserName, err)
+			}
+			keys = append(keys, page.AccessKeyMetadata...)
 		}
+	}
+
+	return keys, truncatedErr
+}
+
+func MatchIamUserKey(accessKeyMetadata types.AccessKeyMetadata, matcher Matcher) bool {
+	return accessKeyMetadata.AccessKeyId != nil && matcher.Match(*accessKeyMetadata.AccessKeyId)
+}
+
+// IamAccessKey is an access key with the last time, service and region it was used in.
+type IamAccessKey struct {
+	types.AccessKeyMetadata
+	// LastUsed is nil when AWS doesn't know when the key was last used
+	LastUsed *types.AccessKeyLastUsed
+}
+
+// GetAccessKeyAccount returns the ID of the account owning an access key, whichever account the credentials
+// of the config belong to.
+func GetAccessKeyAccount(ctx context.Context, config aws.Config, region string, accessKeyId string) (string, error) {
+	config.Region = region
+	stsClient := sts.NewFromConfig(config)
+
+	output, err := stsClient.GetAccessKeyInfo(ctx, &sts.GetAccessKeyInfoInput{AccessKeyId: aws.String(accessKeyId)})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the account of access key %s: %w", accessKeyId, err)
+	}
+	return aws.ToString(output.Account), nil
+}
+
+// GetIamUserKey describes an access key of the account, the error wraps ErrNotFound when it doesn't exist.
+// Its user is looked up from when the key was last used, so only the keys of that user are listed.
+func GetIamUserKey(ctx context.Context, config aws.Config, region string, accessKeyId string) (IamAccessKey, error) {
+	config.Region = region
+	iamClient := iam.NewFromConfig(config)
 
-		for _, accessKeyMetadata := range accessKeys.AccessKeyMetadata {
-			if strings.Contains(*accessKeyMetadata.AccessKeyId, searchValue) {
-				filteredAccessKeys[*user.UserName] = *accessKeyMetadata.AccessKeyId
+	lastUsed, err := iamClient.GetAccessKeyLastUsed(ctx, &iam.GetAccessKeyLastUsedInput{AccessKeyId: aws.String(accessKeyId)})
+	if err != nil {
+		return IamAccessKey{}, notFoundError(err)
+	}
+	key := IamAccessKey{
+		AccessKeyMetadata: types.AccessKeyMetadata{AccessKeyId: aws.String(accessKeyId), UserName: lastUsed.UserName},
+		LastUsed:          lastUsed.AccessKeyLastUsed,
+	}
+	// without a user, e.g. for the keys of the root user, there is nothing more to list
+	if lastUsed.UserName == nil {
+		return key, nil
+	}
+
+	paginator := iam.NewListAccessKeysPaginator(iamClient, &iam.ListAccessKeysInput{UserName: lastUsed.UserName})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return IamAccessKey{}, fmt.Errorf("failed to list access keys for user %s: %w", *lastUsed.UserName, notFoundError(err))
+		}
+		for _, metadata := range page.AccessKeyMetadata {
+			if aws.ToString(metadata.AccessKeyId) == accessKeyId {
+				key.AccessKeyMetadata = metadata
+				return key, nil
 			}
 		}
 	}
+	return IamAccessKey{}, fmt.Errorf("access key %s: %w", accessKeyId, ErrNotFound)
+}
+
+// GetAccountAlias returns the IAM alias of the account, or an empty string when it has none.
+func GetAccountAlias(ctx context.Context, config aws.Config) (string, error) {
+	iamClient := iam.NewFromConfig(config)
 
-	return filteredAccessKeys, nil
+	output, err := iamClient.ListAccountAliases(ctx, &iam.ListAccountAliasesInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list IAM account aliases: %w", err)
+	}
+	// an account has at most one alias
+	if len(output.AccountAliases) == 0 {
+		return "", nil
+	}
+	return output.AccountAliases[0], nil
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/transport/http"
@@ -12,46 +14,192 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 )
 
-func FindLoadBalancer(config aws.Config, region string, searchValue string) ([][]string, error) {
+// LoadBalancer is the common shape of classic (v1) and application/network (v2) load balancers.
+type LoadBalancer struct {
+	Name        string
+	DnsName     string
+	Arn         string
+	CreatedTime *time.Time
+	Tags        map[string]string
+}
+
+// describeTagsBatch is the most load balancers DescribeTags takes per call, in both APIs.
+const describeTagsBatch = 20
+
+func ListLoadBalancers(ctx context.Context, config aws.Config, region string, maxPages int) ([]LoadBalancer, error) {
 	config.Region = region
+	loadBalancers := []LoadBalancer{}
 
 	elbv2Client := elasticloadbalancingv2.NewFromConfig(config)
-	v2_output, err := elbv2Client.DescribeLoadBalancers(context.TODO(), &elasticloadbalancingv2.DescribeLoadBalancersInput{})
-	if err != nil {
-		var accessDeniedErr *http.ResponseError
-		if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
-			return nil, nil
+	v2_paginator := elasticloadbalancingv2.NewDescribeLoadBalancersPaginator(elbv2Client, &elasticloadbalancingv2.DescribeLoadBalancersInput{})
+	var v2_err error
+	for pages := 0; v2_paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			v2_err = truncatedError("DescribeLoadBalancers (v2)", maxPages)
+			break
+		}
+		page, err := v2_paginator.NextPage(ctx)
+		if err != nil {
+			var accessDeniedErr *http.ResponseError
+			if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
+				return nil, err
+			}
+			log.Printf("Unable to list load balancers (v2), %v", err)
+			v2_err = err
+			break
+		}
+
+		for _, lb := range page.LoadBalancers {
+			loadBalancers = append(loadBalancers, LoadBalancer{
+				Name:        aws.ToString(lb.LoadBalancerName),
+				DnsName:     aws.ToString(lb.DNSName),
+				Arn:         aws.ToString(lb.LoadBalancerArn),
+				CreatedTime: lb.CreatedTime,
+			})
 		}
-		fmt.Printf("Unable to list load balancers (v2), %v", err)
 	}
 
+	addLoadBalancerV2Tags(ctx, elbv2Client, loadBalancers)
+	v2Count := len(loadBalancers)
+
 	elbv1Client := elasticloadbalancing.NewFromConfig(config)
-	v1_output, err := elbv1Client.DescribeLoadBalancers(context.TODO(), &elasticloadbalancing.DescribeLoadBalancersInput{})
-	if err != nil {
-		var accessDeniedErr *http.ResponseError
-		if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
-			return nil, nil
+	v1_paginator := elasticloadbalancing.NewDescribeLoadBalancersPaginator(elbv1Client, &elasticloadbalancing.DescribeLoadBalancersInput{})
+	var v1_err error
+	for pages := 0; v1_paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			v1_err = truncatedError("DescribeLoadBalancers (v1)", maxPages)
+			break
+		}
+		page, err := v1_paginator.NextPage(ctx)
+		if err != nil {
+			var accessDeniedErr *http.ResponseError
+			if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
+				return nil, err
+			}
+			log.Printf("Unable to list load balancers (v1), %v", err)
+			v1_err = err
+			break
+		}
+
+		for _, lb := range page.LoadBalancerDescriptions {
+			loadBalancers = append(loadBalancers, LoadBalancer{
+				Name:        aws.ToString(lb.LoadBalancerName),
+				DnsName:     aws.ToString(lb.DNSName),
+				CreatedTime: lb.CreatedTime,
+			})
+		}
+	}
+
+	addLoadBalancerV1Tags(ctx, elbv1Client, loadBalancers[v2Count:])
+
+	// whatever was listed is still returned when only one of the APIs failed
+	return loadBalancers, errors.Join(v2_err, v1_err)
+}
+
+// GetLoadBalancer describes a single load balancer, by its ARN for application, network and gateway load
+// balancers or by its name for classic ones. The error wraps ErrNotFound when it doesn't exist.
+func GetLoadBalancer(ctx context.Context, config aws.Config, region string, arnOrName string) (LoadBalancer, error) {
+	config.Region = region
+
+	if strings.HasPrefix(arnOrName, "arn:") {
+		elbv2Client := elasticloadbalancingv2.NewFromConfig(config)
+		output, err := elbv2Client.DescribeLoadBalancers(ctx, &elasticloadbalancingv2.DescribeLoadBalancersInput{LoadBalancerArns: []string{arnOrName}})
+		if err != nil {
+			return LoadBalancer{}, notFoundError(err)
+		}
+		if len(output.LoadBalancers) == 0 {
+			return LoadBalancer{}, fmt.Errorf("load balancer %s: %w", arnOrName, ErrNotFound)
 		}
-		fmt.Printf("Unable to list load balancers (v1), %v", err)
+		lb := output.LoadBalancers[0]
+		loadBalancers := []LoadBalancer{{
+			Name:        aws.ToString(lb.LoadBalancerName),
+			DnsName:     aws.ToString(lb.DNSName),
+			Arn:         aws.ToString(lb.LoadBalancerArn),
+			CreatedTime: lb.CreatedTime,
+		}}
+		addLoadBalancerV2Tags(ctx, elbv2Client, loadBalancers)
+		return loadBalancers[0], nil
+	}
+
+	elbv1Client := elasticloadbalancing.NewFromConfig(config)
+	output, err := elbv1Client.DescribeLoadBalancers(ctx, &elasticloadbalancing.DescribeLoadBalancersInput{LoadBalancerNames: []string{arnOrName}})
+	if err != nil {
+		return LoadBalancer{}, notFoundError(err)
 	}
+	if len(output.LoadBalancerDescriptions) == 0 {
+		return LoadBalancer{}, fmt.Errorf("load balancer %s: %w", arnOrName, ErrNotFound)
+	}
+	lb := output.LoadBalancerDescriptions[0]
+	loadBalancers := []LoadBalancer{{
+		Name:        aws.ToString(lb.LoadBalancerName),
+		DnsName:     aws.ToString(lb.DNSName),
+		CreatedTime: lb.CreatedTime,
+	}}
+	addLoadBalancerV1Tags(ctx, elbv1Client, loadBalancers)
+	return loadBalancers[0], nil
+}
+
+// addLoadBalancerV2Tags sets the tags of application, network and gateway load balancers. Load balancers
+// whose tags can't be read are left without tags.
+func addLoadBalancerV2Tags(ctx context.Context, client *elasticloadbalancingv2.Client, loadBalancers []LoadBalancer) {
+	for _, batch := range batches(len(loadBalancers), describeTagsBatch) {
+		byArn := map[string]*LoadBalancer{}
+		for i := batch[0]; i < batch[1]; i++ {
+			byArn[loadBalancers[i].Arn] = &loadBalancers[i]
+		}
+		arns := make([]string, 0, len(byArn))
+		for arn := range byArn {
+			arns = append(arns, arn)
+		}
 
-	filteredLoadBalancers := [][]string{}
-	if v2_output != nil {
-		for _, lb := range v2_output.LoadBalancers {
-			if strings.Contains(*lb.LoadBalancerArn, searchValue) || strings.Contains(*lb.DNSName, searchValue) || strings.Contains(*lb.LoadBalancerArn, searchValue) {
-				filteredLoadBalancers = append(filteredLoadBalancers, []string{*lb.LoadBalancerName, *lb.DNSName})
-				fmt.Println(*lb.LoadBalancerName)
+		output, err := client.DescribeTags(ctx, &elasticloadbalancingv2.DescribeTagsInput{ResourceArns: arns})
+		if err != nil {
+			log.Printf("Unable to describe the tags of load balancers (v2), %v", err)
+			return
+		}
+		for _, description := range output.TagDescriptions {
+			lb, ok := byArn[aws.ToString(description.ResourceArn)]
+			if !ok || len(description.Tags) == 0 {
+				continue
+			}
+			lb.Tags = map[string]string{}
+			for _, tag := range description.Tags {
+				lb.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
 			}
 		}
 	}
-	if v1_output != nil {
-		for _, lb := range v1_output.LoadBalancerDescriptions {
-			if strings.Contains(*lb.LoadBalancerName, searchValue) || strings.Contains(*lb.DNSName, searchValue) {
-				filteredLoadBalancers = append(filteredLoadBalancers, []string{*lb.LoadBalancerName, *lb.DNSName})
-				fmt.Println(*lb.LoadBalancerName)
+}
+
+// addLoadBalancerV1Tags sets the tags of classic load balancers, like addLoadBalancerV2Tags.
+func addLoadBalancerV1Tags(ctx context.Context, client *elasticloadbalancing.Client, loadBalancers []LoadBalancer) {
+	for _, batch := range batches(len(loadBalancers), describeTagsBatch) {
+		byName := map[string]*LoadBalancer{}
+		for i := batch[0]; i < batch[1]; i++ {
+			byName[loadBalancers[i].Name] = &loadBalancers[i]
+		}
+		names := make([]string, 0, len(byName))
+		for name := range byName {
+			names = append(names, name)
+		}
+
+		output, err := client.DescribeTags(ctx, &elasticloadbalancing.DescribeTagsInput{LoadBalancerNames: names})
+		if err != nil {
+			log.Printf("Unable to describe the tags of load balancers (v1), %v", err)
+			return
+		}
+		for _, description := range output.TagDescriptions {
+			lb, ok := byName[aws.ToString(description.LoadBalancerName)]
+			if !ok || len(description.Tags) == 0 {
+				continue
+			}
+			lb.Tags = map[string]string{}
+			for _, tag := range description.Tags {
+				lb.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
 			}
 		}
 	}
+}
 
-	return filteredLoadBalancers, nil
+func MatchLoadBalancer(lb LoadBalancer, matcher Matcher) bool {
+	return matcher.Match(lb.Name, lb.DnsName, lb.Arn) || matcher.MatchTags(lb.Tags)
 }
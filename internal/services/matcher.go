@@ -0,0 +1,175 @@
+package services
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// Match modes
+const (
+	MatchSubstring = "substring"
+	MatchExact     = "exact"
+	MatchPrefix    = "prefix"
+	MatchRegex     = "regex"
+	MatchGlob      = "glob"
+)
+
+// MatchOptions choose how search values are compared with the values of the resources.
+type MatchOptions struct {
+	// Mode is one of the match modes, substring when empty
+	Mode string
+	// CaseSensitive makes the exact, prefix, regex and glob modes case-sensitive, substring never is
+	CaseSensitive bool
+}
+
+// Matcher compares a search value with the values of resources, it is shared by every Match function
+// so all the resource types handle modes and case the same way.
+type Matcher struct {
+	mode          string
+	value         string
+	caseSensitive bool
+	re            *regexp.Regexp
+	network       netip.Prefix
+	isNetwork     bool
+	// tagKey and tagValue match the tags of a Key=Value search value
+	tagKey   string
+	tagValue *Matcher
+}
+
+// NewMatcher prepares the matching of a search value, it fails on an unknown mode or an invalid pattern.
+func NewMatcher(searchValue string, options MatchOptions) (Matcher, error) {
+	m := Matcher{mode: options.Mode, value: searchValue, caseSensitive: options.CaseSensitive}
+	if m.mode == "" {
+		m.mode = MatchSubstring
+	}
+
+	flags := "(?i)"
+	if m.caseSensitive {
+		flags = ""
+	}
+
+	switch m.mode {
+	case MatchSubstring:
+		m.caseSensitive = false
+		m.value = strings.ToLower(searchValue)
+	case MatchExact, MatchPrefix:
+	case MatchRegex:
+		re, err := regexp.Compile(flags + searchValue)
+		if err != nil {
+			return Matcher{}, fmt.Errorf("invalid regular expression %q: %w", searchValue, err)
+		}
+		m.re = re
+	case MatchGlob:
+		re, err := regexp.Compile(flags + globToRegexp(searchValue))
+		if err != nil {
+			return Matcher{}, fmt.Errorf("invalid glob %q: %w", searchValue, err)
+		}
+		m.re = re
+	default:
+		return Matcher{}, fmt.Errorf("unknown match mode %q: expected substring, exact, prefix, regex or glob", m.mode)
+	}
+
+	// an IP address or a CIDR block is matched as a network, unless it is a pattern
+	if m.re == nil {
+		m.network, m.isNetwork = parseNetwork(searchValue)
+	}
+
+	// Key=Value matches the value of a tag and =Value the value of any tag, = is left to regular expressions
+	if key, value, ok := strings.Cut(searchValue, "="); ok && m.mode != MatchRegex {
+		tagValue, err := NewMatcher(value, options)
+		if err != nil {
+			return Matcher{}, err
+		}
+		m.tagKey, m.tagValue = key, &tagValue
+	}
+	return m, nil
+}
+
+// SubstringMatcher matches the search value as a case-insensitive substring, the default mode.
+func SubstringMatcher(searchValue string) Matcher {
+	m, _ := NewMatcher(searchValue, MatchOptions{Mode: MatchSubstring})
+	return m
+}
+
+// globToRegexp translates a glob, where * matches any text and ? any character, into an anchored regular expression.
+func globToRegexp(glob string) string {
+	var re strings.Builder
+	re.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+	return re.String()
+}
+
+// Match reports whether any of the values matches.
+func (m Matcher) Match(values ...string) bool {
+	for _, value := range values {
+		if m.matchValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m Matcher) matchValue(value string) bool {
+	switch m.mode {
+	case MatchSubstring:
+		return strings.Contains(strings.ToLower(value), m.value)
+	case MatchExact:
+		if m.caseSensitive {
+			return value == m.value
+		}
+		return strings.EqualFold(value, m.value)
+	case MatchPrefix:
+		if m.caseSensitive {
+			return strings.HasPrefix(value, m.value)
+		}
+		return strings.HasPrefix(strings.ToLower(value), strings.ToLower(m.value))
+	default:
+		return m.re.MatchString(value)
+	}
+}
+
+// MatchTags reports whether any of the tags matches. A search value of the form Key=Value matches the tags
+// with that key (case-insensitive unless case-sensitive) and a matching value, =Value matches the value of
+// any tag, and any other search value matches the key or the value of a tag.
+func (m Matcher) MatchTags(tags map[string]string) bool {
+	for key, value := range tags {
+		if m.tagValue != nil && m.matchTagKey(key) && m.tagValue.Match(value) {
+			return true
+		}
+		if m.Match(key, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m Matcher) matchTagKey(key string) bool {
+	if m.tagKey == "" {
+		return true
+	}
+	if m.caseSensitive {
+		return key == m.tagKey
+	}
+	return strings.EqualFold(key, m.tagKey)
+}
+
+// MatchNetwork reports whether the search value is an IP address or a CIDR block (ok) and, if so, whether any
+// of the addresses lies within it or any of the CIDR blocks overlaps it.
+func (m Matcher) MatchNetwork(addresses []string, cidrs []string) (matched bool, ok bool) {
+	if !m.isNetwork {
+		return false, false
+	}
+	return anyAddressInNetwork(addresses, m.network) || anyCidrOverlapsNetwork(cidrs, m.network), true
+}
@@ -0,0 +1,136 @@
+package services
+
+import "testing"
+
+func TestMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		searchValue string
+		options     MatchOptions
+		value       string
+		want        bool
+	}{
+		{name: "substring by default", searchValue: "web", value: "prod-web-1", want: true},
+		{name: "substring", searchValue: "web", options: MatchOptions{Mode: MatchSubstring}, value: "prod-web-1", want: true},
+		{name: "substring mismatch", searchValue: "db", options: MatchOptions{Mode: MatchSubstring}, value: "prod-web-1", want: false},
+		{name: "substring ignores case", searchValue: "WEB", value: "prod-web-1", want: true},
+		{name: "substring is never case-sensitive", searchValue: "WEB", options: MatchOptions{Mode: MatchSubstring, CaseSensitive: true}, value: "prod-web-1", want: true},
+		{name: "exact", searchValue: "prod-web-1", options: MatchOptions{Mode: MatchExact}, value: "prod-web-1", want: true},
+		{name: "exact is not a substring", searchValue: "web", options: MatchOptions{Mode: MatchExact}, value: "prod-web-1", want: false},
+		{name: "exact ignores case", searchValue: "PROD-WEB-1", options: MatchOptions{Mode: MatchExact}, value: "prod-web-1", want: true},
+		{name: "exact case-sensitive", searchValue: "PROD-WEB-1", options: MatchOptions{Mode: MatchExact, CaseSensitive: true}, value: "prod-web-1", want: false},
+		{name: "prefix", searchValue: "prod-", options: MatchOptions{Mode: MatchPrefix}, value: "prod-web-1", want: true},
+		{name: "prefix is not a suffix", searchValue: "web-1", options: MatchOptions{Mode: MatchPrefix}, value: "prod-web-1", want: false},
+		{name: "prefix ignores case", searchValue: "PROD", options: MatchOptions{Mode: MatchPrefix}, value: "prod-web-1", want: true},
+		{name: "prefix case-sensitive", searchValue: "PROD", options: MatchOptions{Mode: MatchPrefix, CaseSensitive: true}, value: "prod-web-1", want: false},
+		{name: "regex", searchValue: `^prod-web-\d+$`, options: MatchOptions{Mode: MatchRegex}, value: "prod-web-12", want: true},
+		{name: "regex is unanchored", searchValue: `web-\d`, options: MatchOptions{Mode: MatchRegex}, value: "prod-web-1", want: true},
+		{name: "regex mismatch", searchValue: `^web`, options: MatchOptions{Mode: MatchRegex}, value: "prod-web-1", want: false},
+		{name: "regex ignores case", searchValue: `^PROD`, options: MatchOptions{Mode: MatchRegex}, value: "prod-web-1", want: true},
+		{name: "regex case-sensitive", searchValue: `^PROD`, options: MatchOptions{Mode: MatchRegex, CaseSensitive: true}, value: "prod-web-1", want: false},
+		{name: "regex with its own (?i)", searchValue: `(?i)^PROD`, options: MatchOptions{Mode: MatchRegex, CaseSensitive: true}, value: "prod-web-1", want: true},
+		{name: "glob star", searchValue: "prod-*", options: MatchOptions{Mode: MatchGlob}, value: "prod-web-1", want: true},
+		{name: "glob question mark", searchValue: "prod-web-?", options: MatchOptions{Mode: MatchGlob}, value: "prod-web-1", want: true},
+		{name: "glob question mark is one character", searchValue: "prod-web-?", options: MatchOptions{Mode: MatchGlob}, value: "prod-web-12", want: false},
+		{name: "glob is anchored", searchValue: "web*", options: MatchOptions{Mode: MatchGlob}, value: "prod-web-1", want: false},
+		{name: "glob ignores case", searchValue: "PROD-*", options: MatchOptions{Mode: MatchGlob}, value: "prod-web-1", want: true},
+		{name: "glob case-sensitive", searchValue: "PROD-*", options: MatchOptions{Mode: MatchGlob, CaseSensitive: true}, value: "prod-web-1", want: false},
+		{name: "glob dot is literal", searchValue: "api.example.com", options: MatchOptions{Mode: MatchGlob}, value: "apixexample.com", want: false},
+		{name: "glob dot matches itself", searchValue: "*.example.com", options: MatchOptions{Mode: MatchGlob}, value: "api.example.com", want: true},
+		{name: "glob brackets are literal", searchValue: "web[1]", options: MatchOptions{Mode: MatchGlob}, value: "web[1]", want: true},
+		{name: "glob brackets are not a class", searchValue: "web[1]", options: MatchOptions{Mode: MatchGlob}, value: "web1", want: false},
+		{name: "glob regexp operators are literal", searchValue: "a+b(c)|$", options: MatchOptions{Mode: MatchGlob}, value: "a+b(c)|$", want: true},
+		{name: "glob unbalanced parenthesis is literal", searchValue: "web(*", options: MatchOptions{Mode: MatchGlob}, value: "web(1", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.searchValue, tt.options)
+			if err != nil {
+				t.Fatalf("NewMatcher(%q, %+v): %v", tt.searchValue, tt.options, err)
+			}
+			if got := m.Match(tt.value); got != tt.want {
+				t.Errorf("NewMatcher(%q, %+v).Match(%q) = %v, want %v", tt.searchValue, tt.options, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMatcherErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		searchValue string
+		options     MatchOptions
+	}{
+		{name: "invalid regex", searchValue: "web[", options: MatchOptions{Mode: MatchRegex}},
+		{name: "invalid regex repetition", searchValue: "*web", options: MatchOptions{Mode: MatchRegex}},
+		{name: "invalid case-sensitive regex", searchValue: "web(", options: MatchOptions{Mode: MatchRegex, CaseSensitive: true}},
+		{name: "unknown mode", searchValue: "web", options: MatchOptions{Mode: "fuzzy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewMatcher(tt.searchValue, tt.options); err == nil {
+				t.Errorf("NewMatcher(%q, %+v) succeeded, want an error", tt.searchValue, tt.options)
+			}
+		})
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{glob: "", want: `^$`},
+		{glob: "web", want: `^web$`},
+		{glob: "web-*", want: `^web-.*$`},
+		{glob: "web-?", want: `^web-.$`},
+		{glob: "*.example.com", want: `^.*\.example\.com$`},
+		{glob: "a+b(c)[d]{e}|^$\\", want: `^a\+b\(c\)\[d\]\{e\}\|\^\$\\$`},
+	}
+
+	for _, tt := range tests {
+		if got := globToRegexp(tt.glob); got != tt.want {
+			t.Errorf("globToRegexp(%q) = %q, want %q", tt.glob, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherMatchTags(t *testing.T) {
+	tags := map[string]string{"Team": "payments", "Env": "prod"}
+
+	tests := []struct {
+		name        string
+		searchValue string
+		options     MatchOptions
+		want        bool
+	}{
+		{name: "key and value", searchValue: "Team=payments", want: true},
+		{name: "key and value substring", searchValue: "Team=pay", want: true},
+		{name: "key and other value", searchValue: "Team=data", want: false},
+		{name: "value of another key", searchValue: "Env=payments", want: false},
+		{name: "key ignores case", searchValue: "team=payments", want: true},
+		{name: "key case-sensitive", searchValue: "team=payments", options: MatchOptions{Mode: MatchExact, CaseSensitive: true}, want: false},
+		{name: "exact value", searchValue: "Team=pay", options: MatchOptions{Mode: MatchExact}, want: false},
+		{name: "glob value", searchValue: "Team=pay*", options: MatchOptions{Mode: MatchGlob}, want: true},
+		{name: "value of any tag", searchValue: "=prod", want: true},
+		{name: "empty value", searchValue: "Team=", want: true},
+		{name: "bare key", searchValue: "Env", want: true},
+		{name: "bare value", searchValue: "payments", want: true},
+		{name: "neither key nor value", searchValue: "owner", want: false},
+		{name: "regex keeps =", searchValue: "Team=payments", options: MatchOptions{Mode: MatchRegex}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.searchValue, tt.options)
+			if err != nil {
+				t.Fatalf("NewMatcher(%q, %+v): %v", tt.searchValue, tt.options, err)
+			}
+			if got := m.MatchTags(tags); got != tt.want {
+				t.Errorf("NewMatcher(%q, %+v).MatchTags(%v) = %v, want %v", tt.searchValue, tt.options, tags, got, tt.want)
+			}
+		})
+	}
+}
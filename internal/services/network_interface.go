@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func ListNetworkInterfaces(ctx context.Context, config aws.Config, region string, maxPages int) ([]types.NetworkInterface, error) {
+	config.Region = region
+
+	ec2Client := ec2.NewFromConfig(config)
+	input := &ec2.DescribeNetworkInterfacesInput{}
+	networkInterfaces := []types.NetworkInterface{}
+
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(ec2Client, input)
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			return networkInterfaces, truncatedError("DescribeNetworkInterfaces", maxPages)
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		networkInterfaces = append(networkInterfaces, page.NetworkInterfaces...)
+	}
+
+	return networkInterfaces, nil
+}
+
+// GetNetworkInterface describes a single network interface, the error wraps ErrNotFound when it doesn't exist.
+func GetNetworkInterface(ctx context.Context, config aws.Config, region string, networkInterfaceId string) (types.NetworkInterface, error) {
+	config.Region = region
+
+	ec2Client := ec2.NewFromConfig(config)
+	output, err := ec2Client.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{NetworkInterfaceIds: []string{networkInterfaceId}})
+	if err != nil {
+		return types.NetworkInterface{}, notFoundError(err)
+	}
+	if len(output.NetworkInterfaces) == 0 {
+		return types.NetworkInterface{}, fmt.Errorf("network interface %s: %w", networkInterfaceId, ErrNotFound)
+	}
+	return output.NetworkInterfaces[0], nil
+}
+
+// NetworkInterfaceAddresses returns the private, public and IPv6 addresses assigned to a network interface.
+func NetworkInterfaceAddresses(eni types.NetworkInterface) (privateIps []string, publicIps []string, ipv6s []string) {
+	for _, privateIp := range eni.PrivateIpAddresses {
+		if privateIp.PrivateIpAddress != nil {
+			privateIps = append(privateIps, *privateIp.PrivateIpAddress)
+		}
+		if privateIp.Association != nil && privateIp.Association.PublicIp != nil {
+			publicIps = append(publicIps, *privateIp.Association.PublicIp)
+		}
+	}
+	if len(privateIps) == 0 && eni.PrivateIpAddress != nil {
+		privateIps = append(privateIps, *eni.PrivateIpAddress)
+	}
+	if len(publicIps) == 0 && eni.Association != nil && eni.Association.PublicIp != nil {
+		publicIps = append(publicIps, *eni.Association.PublicIp)
+	}
+	for _, ipv6 := range eni.Ipv6Addresses {
+		if ipv6.Ipv6Address != nil {
+			ipv6s = append(ipv6s, *ipv6.Ipv6Address)
+		}
+	}
+	return privateIps, publicIps, ipv6s
+}
+
+func MatchNetworkInterface(eni types.NetworkInterface, matcher Matcher) bool {
+	privateIps, publicIps, ipv6s := NetworkInterfaceAddresses(eni)
+	addresses := append(append(append([]string{}, privateIps...), publicIps...), ipv6s...)
+
+	if matched, ok := matcher.MatchNetwork(addresses, nil); ok {
+		return matched
+	}
+	return matcher.Match(addresses...) || matcher.MatchTags(Ec2Tags(eni.TagSet))
+}
+
+// networkInterfaceDescriptionPrefixes maps the descriptions AWS services give to the
+// interfaces they create (as type "interface") to the service behind them.
+var networkInterfaceDescriptionPrefixes = []struct {
+	prefix  string
+	service string
+}{
+	{"ELB ", "elb"},
+	{"RDSNetworkInterface", "rds"},
+	{"Amazon EKS", "eks"},
+	{"aws-K8S-", "eks"},
+	{"AWS Lambda VPC ENI", "lambda"},
+	{"arn:aws:ecs:", "ecs"},
+	{"ElastiCache", "elasticache"},
+	{"EFS mount target", "efs"},
+	{"VPC Endpoint Interface", "vpc_endpoint"},
+	{"Interface for NAT Gateway", "nat_gateway"},
+}
+
+// NetworkInterfaceService guesses which AWS service owns a network interface, from its
+// interface type, its description and what it is attached to.
+func NetworkInterfaceService(eni types.NetworkInterface) string {
+	switch eni.InterfaceType {
+	case types.NetworkInterfaceTypeNatGateway:
+		return "nat_gateway"
+	case types.NetworkInterfaceTypeLoadBalancer, types.NetworkInterfaceTypeNetworkLoadBalancer, types.NetworkInterfaceTypeGatewayLoadBalancer:
+		return "elb"
+	case types.NetworkInterfaceTypeVpcEndpoint, types.NetworkInterfaceTypeGatewayLoadBalancerEndpoint:
+		return "vpc_endpoint"
+	case types.NetworkInterfaceTypeLambda:
+		return "lambda"
+	case types.NetworkInterfaceTypeTransitGateway:
+		return "transit_gateway"
+	}
+
+	description := aws.ToString(eni.Description)
+	for _, p := range networkInterfaceDescriptionPrefixes {
+		if strings.HasPrefix(description, p.prefix) {
+			return p.service
+		}
+	}
+
+	if eni.Attachment != nil && eni.Attachment.InstanceId != nil {
+		return "ec2"
+	}
+	return string(eni.InterfaceType)
+}
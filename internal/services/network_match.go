@@ -0,0 +1,66 @@
+package services
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// parseNetwork parses a search value that is an IP address or a CIDR block, IPv4 or IPv6.
+// A single address is returned as the prefix covering just that address.
+func parseNetwork(searchValue string) (netip.Prefix, bool) {
+	searchValue = strings.TrimSpace(searchValue)
+
+	if strings.Contains(searchValue, "/") {
+		prefix, err := netip.ParsePrefix(searchValue)
+		if err != nil {
+			return netip.Prefix{}, false
+		}
+		return prefix.Masked(), true
+	}
+
+	addr, err := netip.ParseAddr(searchValue)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+// addressInNetwork reports whether an IP address lies within the searched network.
+func addressInNetwork(address string, network netip.Prefix) bool {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+	return network.Contains(addr.Unmap())
+}
+
+// anyAddressInNetwork reports whether any of the IP addresses lies within the searched network.
+func anyAddressInNetwork(addresses []string, network netip.Prefix) bool {
+	for _, address := range addresses {
+		if addressInNetwork(address, network) {
+			return true
+		}
+	}
+	return false
+}
+
+// cidrOverlapsNetwork reports whether a CIDR block overlaps the searched network:
+// it contains the searched address, or it lies within (or around) the searched CIDR block.
+func cidrOverlapsNetwork(cidr string, network netip.Prefix) bool {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return false
+	}
+	return prefix.Masked().Overlaps(network)
+}
+
+// anyCidrOverlapsNetwork reports whether any of the CIDR blocks overlaps the searched network.
+func anyCidrOverlapsNetwork(cidrs []string, network netip.Prefix) bool {
+	for _, cidr := range cidrs {
+		if cidrOverlapsNetwork(cidr, network) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package services
+
+import "testing"
+
+func TestMatcherMatchNetwork(t *testing.T) {
+	tests := []struct {
+		name        string
+		searchValue string
+		options     MatchOptions
+		addresses   []string
+		cidrs       []string
+		want        bool
+		wantOk      bool
+	}{
+		{name: "address equal", searchValue: "10.2.0.5", addresses: []string{"10.2.0.5"}, want: true, wantOk: true},
+		{name: "address different", searchValue: "10.2.0.5", addresses: []string{"10.2.0.50"}, want: false, wantOk: true},
+		{name: "address in CIDR block", searchValue: "10.2.0.5", cidrs: []string{"10.2.0.0/24"}, want: true, wantOk: true},
+		{name: "address outside CIDR block", searchValue: "10.3.0.5", cidrs: []string{"10.2.0.0/24"}, want: false, wantOk: true},
+		{name: "CIDR block contains address", searchValue: "10.2.0.0/16", addresses: []string{"10.3.0.5", "10.2.7.1"}, want: true, wantOk: true},
+		{name: "CIDR block misses address", searchValue: "10.2.0.0/16", addresses: []string{"10.3.0.5"}, want: false, wantOk: true},
+		{name: "CIDR block contains CIDR block", searchValue: "10.0.0.0/8", cidrs: []string{"10.2.0.0/24"}, want: true, wantOk: true},
+		{name: "CIDR block within CIDR block", searchValue: "10.2.0.0/28", cidrs: []string{"10.2.0.0/24"}, want: true, wantOk: true},
+		{name: "disjoint CIDR blocks", searchValue: "10.3.0.0/16", cidrs: []string{"10.2.0.0/16"}, want: false, wantOk: true},
+		{name: "host bits of the CIDR block are masked", searchValue: "10.2.3.4/16", addresses: []string{"10.2.200.1"}, want: true, wantOk: true},
+		{name: "host bits of a resource CIDR block are masked", searchValue: "10.2.0.5", cidrs: []string{"10.2.0.9/24"}, want: true, wantOk: true},
+		{name: "space around the search value", searchValue: " 10.2.0.5 ", addresses: []string{"10.2.0.5"}, want: true, wantOk: true},
+		{name: "invalid resource values", searchValue: "10.2.0.0/16", addresses: []string{"web", ""}, cidrs: []string{"10.2.0.0/99"}, want: false, wantOk: true},
+		{name: "IPv6 address", searchValue: "2001:db8::1", addresses: []string{"2001:db8::1"}, want: true, wantOk: true},
+		{name: "IPv6 address in CIDR block", searchValue: "2001:db8::1", cidrs: []string{"2001:db8::/32"}, want: true, wantOk: true},
+		{name: "IPv6 CIDR block contains address", searchValue: "2001:db8::/32", addresses: []string{"2001:db8:1::5"}, want: true, wantOk: true},
+		{name: "IPv6 CIDR block misses address", searchValue: "2001:db8::/32", addresses: []string{"2001:db9::5"}, want: false, wantOk: true},
+		{name: "IPv6 CIDR block misses IPv4 address", searchValue: "::/0", addresses: []string{"10.2.0.5"}, want: false, wantOk: true},
+		{name: "IPv4-mapped IPv6 address", searchValue: "::ffff:10.2.0.5", addresses: []string{"10.2.0.5"}, want: true, wantOk: true},
+		{name: "IPv4-mapped resource address", searchValue: "10.2.0.0/16", addresses: []string{"::ffff:10.2.0.5"}, want: true, wantOk: true},
+		{name: "text is not a network", searchValue: "web", addresses: []string{"10.2.0.5"}, want: false, wantOk: false},
+		{name: "partial address is not a network", searchValue: "10.2", addresses: []string{"10.2.0.5"}, want: false, wantOk: false},
+		{name: "invalid CIDR block is not a network", searchValue: "10.2.0.0/33", addresses: []string{"10.2.0.5"}, want: false, wantOk: false},
+		{name: "regex is not a network", searchValue: "10.2.0.5", options: MatchOptions{Mode: MatchRegex}, addresses: []string{"10.2.0.5"}, want: false, wantOk: false},
+		{name: "glob is not a network", searchValue: "10.2.0.5", options: MatchOptions{Mode: MatchGlob}, addresses: []string{"10.2.0.5"}, want: false, wantOk: false},
+		{name: "exact address is a network", searchValue: "10.2.0.0/16", options: MatchOptions{Mode: MatchExact}, addresses: []string{"10.2.0.5"}, want: true, wantOk: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.searchValue, tt.options)
+			if err != nil {
+				t.Fatalf("NewMatcher(%q, %+v): %v", tt.searchValue, tt.options, err)
+			}
+			got, ok := m.MatchNetwork(tt.addresses, tt.cidrs)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("MatchNetwork(%v, %v) with %q = %v, %v, want %v, %v", tt.addresses, tt.cidrs, tt.searchValue, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestParseNetwork(t *testing.T) {
+	tests := []struct {
+		searchValue string
+		want        string
+		wantOk      bool
+	}{
+		{searchValue: "10.2.0.5", want: "10.2.0.5/32", wantOk: true},
+		{searchValue: "10.2.3.4/16", want: "10.2.0.0/16", wantOk: true},
+		{searchValue: "2001:db8::1", want: "2001:db8::1/128", wantOk: true},
+		{searchValue: "2001:db8::1/32", want: "2001:db8::/32", wantOk: true},
+		{searchValue: "::ffff:10.2.0.5", want: "10.2.0.5/32", wantOk: true},
+		{searchValue: "10.2.0.0/", wantOk: false},
+		{searchValue: "10.2.0.256", wantOk: false},
+		{searchValue: "2001:db8::/129", wantOk: false},
+		{searchValue: "web", wantOk: false},
+		{searchValue: "", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		prefix, ok := parseNetwork(tt.searchValue)
+		if ok != tt.wantOk || (ok && prefix.String() != tt.want) {
+			t.Errorf("parseNetwork(%q) = %v, %v, want %s, %v", tt.searchValue, prefix, ok, tt.want, tt.wantOk)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/smithy-go"
+)
+
+// OrganizationAccount is a member account of an AWS Organization, with the path of the OUs it belongs to.
+type OrganizationAccount struct {
+	Id     string
+	Name   string
+	Arn    string
+	Status string
+	// OuPath is the "/" separated names of the OUs from the root to the account, e.g. "Root/Workloads/Prod"
+	OuPath string
+}
+
+// ListOrganizationAccounts lists every account of the organization, it must be called with the
+// credentials of the management account or of a delegated administrator.
+func ListOrganizationAccounts(ctx context.Context, config aws.Config) ([]OrganizationAccount, error) {
+	orgClient := organizations.NewFromConfig(config)
+
+	accounts := []OrganizationAccount{}
+	paginator := organizations.NewListAccountsPaginator(orgClient, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization accounts: %w", err)
+		}
+		for _, account := range page.Accounts {
+			accounts = append(accounts, OrganizationAccount{
+				Id:     aws.ToString(account.Id),
+				Name:   aws.ToString(account.Name),
+				Arn:    aws.ToString(account.Arn),
+				Status: string(account.Status),
+			})
+		}
+	}
+
+	ouPaths, err := organizationOuPaths(ctx, orgClient)
+	if err != nil {
+		return nil, err
+	}
+	for i := range accounts {
+		accounts[i].OuPath = ouPaths[accounts[i].Id]
+	}
+
+	return accounts, nil
+}
+
+// GetOrganizationAccountName returns the name of an account in its organization, or an empty string when it
+// is unknown: the account isn't part of an organization, or the credentials aren't the ones of the management
+// account or of a delegated administrator (which are the only ones allowed to describe accounts).
+func GetOrganizationAccountName(ctx context.Context, config aws.Config, accountId string) (string, error) {
+	orgClient := organizations.NewFromConfig(config)
+
+	output, err := orgClient.DescribeAccount(ctx, &organizations.DescribeAccountInput{AccountId: aws.String(accountId)})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.ErrorCode() {
+			case "AccessDeniedException", "AWSOrganizationsNotInUseException", "AccountNotFoundException":
+				return "", nil
+			}
+		}
+		return "", fmt.Errorf("failed to describe organization account %s: %w", accountId, err)
+	}
+	if output.Account == nil {
+		return "", nil
+	}
+	return aws.ToString(output.Account.Name), nil
+}
+
+// organizationOuPaths walks the organization tree from its roots and maps every account ID to its OU path.
+func organizationOuPaths(ctx context.Context, orgClient *organizations.Client) (map[string]string, error) {
+	ouPaths := map[string]string{}
+
+	var walk func(parentId string, path string) error
+	walk = func(parentId string, path string) error {
+		accountsPaginator := organizations.NewListAccountsForParentPaginator(orgClient, &organizations.ListAccountsForParentInput{
+			ParentId: aws.String(parentId),
+		})
+		for accountsPaginator.HasMorePages() {
+			page, err := accountsPaginator.NextPage(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list accounts of %s: %w", path, err)
+			}
+			for _, account := range page.Accounts {
+				ouPaths[aws.ToString(account.Id)] = path
+			}
+		}
+
+		ousPaginator := organizations.NewListOrganizationalUnitsForParentPaginator(orgClient, &organizations.ListOrganizationalUnitsForParentInput{
+			ParentId: aws.String(parentId),
+		})
+		for ousPaginator.HasMorePages() {
+			page, err := ousPaginator.NextPage(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list organizational units of %s: %w", path, err)
+			}
+			for _, ou := range page.OrganizationalUnits {
+				if err := walk(aws.ToString(ou.Id), path+"/"+aws.ToString(ou.Name)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	rootsPaginator := organizations.NewListRootsPaginator(orgClient, &organizations.ListRootsInput{})
+	for rootsPaginator.HasMorePages() {
+		page, err := rootsPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization roots: %w", err)
+		}
+		for _, root := range page.Roots {
+			if err := walk(aws.ToString(root.Id), aws.ToString(root.Name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return ouPaths, nil
+}
+
+// IsActiveAccount reports whether an organization account can be searched (not suspended or being closed).
+func IsActiveAccount(account OrganizationAccount) bool {
+	return account.Status == string(types.AccountStatusActive)
+}
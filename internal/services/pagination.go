@@ -0,0 +1,18 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTruncated is returned, along with the resources listed so far, when a paginated call stopped at its page cap.
+var ErrTruncated = errors.New("listing truncated at the page limit")
+
+// pageLimitReached reports whether a paginated call already walked maxPages pages. Zero maxPages means no limit.
+func pageLimitReached(pages int, maxPages int) bool {
+	return maxPages > 0 && pages >= maxPages
+}
+
+func truncatedError(operation string, maxPages int) error {
+	return fmt.Errorf("%s stopped after %d pages: %w", operation, maxPages, ErrTruncated)
+}
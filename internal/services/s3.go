@@ -4,36 +4,105 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"log"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 )
 
-func FindS3Bucket(config aws.Config, region string, searchValue string) []string {
+// S3Bucket is a bucket together with its region and its tags.
+type S3Bucket struct {
+	types.Bucket
+	Region string
+	Tags   map[string]string
+}
+
+func ListS3Buckets(ctx context.Context, config aws.Config, region string) ([]S3Bucket, error) {
 	config.Region = region
 
 	s3Client := s3.NewFromConfig(config)
-	output, err := s3Client.ListBuckets(context.TODO(), &s3.ListBucketsInput{})
+	output, err := s3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
 	if err != nil {
 		var accessDeniedErr *http.ResponseError
 		if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
-			return nil
+			return nil, err
 		}
-		fmt.Printf("Unable to list buckets, %v", err)
-		return nil
+		log.Printf("Unable to list buckets, %v", err)
+		return nil, err
 	}
 
-	searchValue = strings.ToLower(searchValue)
+	buckets := make([]S3Bucket, len(output.Buckets))
+	forEachConcurrently(len(output.Buckets), func(i int) {
+		buckets[i] = S3Bucket{Bucket: output.Buckets[i]}
+		buckets[i].Region, buckets[i].Tags = s3BucketRegionAndTags(ctx, s3Client, aws.ToString(output.Buckets[i].Name))
+	})
+	return buckets, nil
+}
+
+// GetS3Bucket describes a single bucket of the account, the error wraps ErrNotFound when the account has no
+// such bucket.
+func GetS3Bucket(ctx context.Context, config aws.Config, region string, name string) (S3Bucket, error) {
+	config.Region = region
 
-	filteredS3Buckets := []string{}
-	if output != nil {
-		for _, bucket := range output.Buckets {
-			if strings.Contains(*bucket.Name, searchValue) {
-				filteredS3Buckets = append(filteredS3Buckets, *bucket.Name)
-			}
+	s3Client := s3.NewFromConfig(config)
+	// ListBuckets is the only call that tells when a bucket was created, and whether it belongs to the account
+	output, err := s3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return S3Bucket{}, err
+	}
+	for _, bucket := range output.Buckets {
+		if aws.ToString(bucket.Name) == name {
+			s3Bucket := S3Bucket{Bucket: bucket}
+			s3Bucket.Region, s3Bucket.Tags = s3BucketRegionAndTags(ctx, s3Client, name)
+			return s3Bucket, nil
 		}
 	}
-	return filteredS3Buckets
+	return S3Bucket{}, fmt.Errorf("bucket %s: %w", name, ErrNotFound)
+}
+
+// s3BucketRegionAndTags looks up the region of a bucket, and then its tags in that region. A bucket whose tags
+// can't be read is still listed, without tags.
+func s3BucketRegionAndTags(ctx context.Context, s3Client *s3.Client, name string) (string, map[string]string) {
+	location, err := s3Client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{Bucket: aws.String(name)})
+	if err != nil {
+		log.Printf("Unable to get the location of bucket %s, %v", name, err)
+		return "", nil
+	}
+	region := s3BucketRegion(location.LocationConstraint)
+
+	output, err := s3Client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(name)}, func(o *s3.Options) {
+		o.Region = region
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "NoSuchTagSet" {
+			log.Printf("Unable to get the tags of bucket %s, %v", name, err)
+		}
+		return region, nil
+	}
+
+	tags := map[string]string{}
+	for _, tag := range output.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return region, tags
+}
+
+// s3BucketRegion translates a bucket location constraint into its region, the legacy empty and EU
+// constraints stand for us-east-1 and eu-west-1.
+func s3BucketRegion(constraint types.BucketLocationConstraint) string {
+	switch constraint {
+	case "":
+		return "us-east-1"
+	case types.BucketLocationConstraintEu:
+		return "eu-west-1"
+	}
+	return string(constraint)
+}
+
+func MatchS3Bucket(bucket S3Bucket, matcher Matcher) bool {
+	return matcher.Match(aws.ToString(bucket.Name)) || matcher.MatchTags(bucket.Tags)
 }
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func ListSubnets(ctx context.Context, config aws.Config, region string, maxPages int) ([]types.Subnet, error) {
+	config.Region = region
+
+	ec2Client := ec2.NewFromConfig(config)
+	input := &ec2.DescribeSubnetsInput{}
+	subnets := []types.Subnet{}
+
+	paginator := ec2.NewDescribeSubnetsPaginator(ec2Client, input)
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			return subnets, truncatedError("DescribeSubnets", maxPages)
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, page.Subnets...)
+	}
+
+	return subnets, nil
+}
+
+// GetSubnet describes a single subnet, the error wraps ErrNotFound when it doesn't exist.
+func GetSubnet(ctx context.Context, config aws.Config, region string, subnetId string) (types.Subnet, error) {
+	config.Region = region
+
+	ec2Client := ec2.NewFromConfig(config)
+	output, err := ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{subnetId}})
+	if err != nil {
+		return types.Subnet{}, notFoundError(err)
+	}
+	if len(output.Subnets) == 0 {
+		return types.Subnet{}, fmt.Errorf("subnet %s: %w", subnetId, ErrNotFound)
+	}
+	return output.Subnets[0], nil
+}
+
+// SubnetCidrBlocks returns the IPv4 and IPv6 CIDR blocks of a subnet.
+func SubnetCidrBlocks(subnet types.Subnet) []string {
+	cidrs := []string{}
+	if subnet.CidrBlock != nil {
+		cidrs = append(cidrs, *subnet.CidrBlock)
+	}
+	for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlock != nil {
+			cidrs = append(cidrs, *association.Ipv6CidrBlock)
+		}
+	}
+	return cidrs
+}
+
+func MatchSubnet(subnet types.Subnet, matcher Matcher) bool {
+	// an IP address finds the subnet it belongs to, a CIDR block the subnets overlapping it
+	if matched, ok := matcher.MatchNetwork(nil, SubnetCidrBlocks(subnet)); ok {
+		return matched
+	}
+
+	return matcher.Match(aws.ToString(subnet.SubnetId), aws.ToString(subnet.VpcId)) ||
+		matcher.Match(SubnetCidrBlocks(subnet)...) ||
+		matcher.MatchTags(Ec2Tags(subnet.Tags))
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+)
+
+// TaggedResource is a resource of any service listed by the Resource Groups Tagging API, with its tags.
+type TaggedResource struct {
+	Arn  string
+	Tags map[string]string
+}
+
+// ListTaggedResources lists the resources of every service in a region that have (or had) tags.
+func ListTaggedResources(ctx context.Context, config aws.Config, region string, maxPages int) ([]TaggedResource, error) {
+	config.Region = region
+
+	taggingClient := resourcegroupstaggingapi.NewFromConfig(config)
+	resources := []TaggedResource{}
+
+	paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(taggingClient, &resourcegroupstaggingapi.GetResourcesInput{})
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			return resources, truncatedError("GetResources", maxPages)
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			var accessDeniedErr *http.ResponseError
+			if errors.As(err, &accessDeniedErr) && accessDeniedErr.HTTPStatusCode() == 403 {
+				return nil, err
+			}
+			log.Printf("Unable to list tagged resources, %v", err)
+			return nil, err
+		}
+
+		for _, mapping := range page.ResourceTagMappingList {
+			resources = append(resources, taggedResource(mapping))
+		}
+	}
+
+	return resources, nil
+}
+
+// GetTaggedResource looks a resource of any service up by its ARN, the error wraps ErrNotFound when the
+// Resource Groups Tagging API doesn't know it (it only knows the resources that have, or had, tags).
+func GetTaggedResource(ctx context.Context, config aws.Config, region string, arn string) (TaggedResource, error) {
+	config.Region = region
+
+	taggingClient := resourcegroupstaggingapi.NewFromConfig(config)
+	output, err := taggingClient.GetResources(ctx, &resourcegroupstaggingapi.GetResourcesInput{ResourceARNList: []string{arn}})
+	if err != nil {
+		return TaggedResource{}, err
+	}
+	if len(output.ResourceTagMappingList) == 0 {
+		return TaggedResource{}, fmt.Errorf("%s: %w", arn, ErrNotFound)
+	}
+	return taggedResource(output.ResourceTagMappingList[0]), nil
+}
+
+func taggedResource(mapping types.ResourceTagMapping) TaggedResource {
+	resource := TaggedResource{Arn: aws.ToString(mapping.ResourceARN)}
+	if len(mapping.Tags) > 0 {
+		resource.Tags = map[string]string{}
+		for _, tag := range mapping.Tags {
+			resource.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+	return resource
+}
+
+// MatchTaggedResource matches the tags of a resource: Key=Value matches a tag, =Value the value of any tag and
+// any other search value the key or the value of a tag, or the ARN.
+func MatchTaggedResource(resource TaggedResource, matcher Matcher) bool {
+	return matcher.MatchTags(resource.Tags) || matcher.Match(resource.Arn)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// tagConcurrency caps the concurrent calls of the services whose tags are fetched one resource at a time.
+const tagConcurrency = 10
+
+// Ec2Tags returns EC2 tags by key, nil when there are none. Every resource type exposes its tags this way.
+func Ec2Tags(tags []types.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	tagMap := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagMap[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tagMap
+}
+
+// forEachConcurrently calls fn for every index below count, at most tagConcurrency at a time.
+func forEachConcurrently(count int, fn func(i int)) {
+	semaphore := make(chan struct{}, tagConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// batches splits count items into consecutive [start, end) ranges of at most size items, for the tag APIs
+// that take a limited number of resources per call.
+func batches(count int, size int) [][2]int {
+	ranges := [][2]int{}
+	for start := 0; start < count; start += size {
+		end := start + size
+		if end > count {
+			end = count
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges
+}
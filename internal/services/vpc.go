@@ -2,40 +2,75 @@ package services
 
 import (
 	"context"
-	"strings"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-func FindVpc(config aws.Config, region string, searchValue string) ([]types.Vpc, error) {
+func ListVpcs(ctx context.Context, config aws.Config, region string, maxPages int) ([]types.Vpc, error) {
 	config.Region = region
 
 	ec2Client := ec2.NewFromConfig(config)
 	input := &ec2.DescribeVpcsInput{}
-	filteredVpcs := []types.Vpc{}
+	vpcs := []types.Vpc{}
 
 	paginator := ec2.NewDescribeVpcsPaginator(ec2Client, input)
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(context.TODO())
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if pageLimitReached(pages, maxPages) {
+			return vpcs, truncatedError("DescribeVpcs", maxPages)
+		}
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, err
+			return vpcs, err
 		}
+		vpcs = append(vpcs, page.Vpcs...)
+	}
 
-		for _, vpc := range page.Vpcs {
-			if vpc.CidrBlock != nil && strings.Contains(*vpc.CidrBlock, searchValue) || strings.Contains(*vpc.VpcId, searchValue) {
-				filteredVpcs = append(filteredVpcs, vpc)
-			} else {
-				for _, tag := range vpc.Tags {
-					if tag.Key != nil && strings.Contains(strings.ToLower(*tag.Key), searchValue) || tag.Value != nil && strings.Contains(strings.ToLower(*tag.Value), searchValue) {
-						filteredVpcs = append(filteredVpcs, vpc)
-						break
-					}
-				}
-			}
+	return vpcs, nil
+}
+
+// GetVpc describes a single VPC, the error wraps ErrNotFound when it doesn't exist.
+func GetVpc(ctx context.Context, config aws.Config, region string, vpcId string) (types.Vpc, error) {
+	config.Region = region
+
+	ec2Client := ec2.NewFromConfig(config)
+	output, err := ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{vpcId}})
+	if err != nil {
+		return types.Vpc{}, notFoundError(err)
+	}
+	if len(output.Vpcs) == 0 {
+		return types.Vpc{}, fmt.Errorf("VPC %s: %w", vpcId, ErrNotFound)
+	}
+	return output.Vpcs[0], nil
+}
+
+// VpcCidrBlocks returns the primary and secondary IPv4 CIDR blocks and the IPv6 CIDR blocks of a VPC.
+func VpcCidrBlocks(vpc types.Vpc) []string {
+	cidrs := []string{}
+	if vpc.CidrBlock != nil {
+		cidrs = append(cidrs, *vpc.CidrBlock)
+	}
+	for _, association := range vpc.CidrBlockAssociationSet {
+		if association.CidrBlock != nil && aws.ToString(association.CidrBlock) != aws.ToString(vpc.CidrBlock) {
+			cidrs = append(cidrs, *association.CidrBlock)
+		}
+	}
+	for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlock != nil {
+			cidrs = append(cidrs, *association.Ipv6CidrBlock)
 		}
 	}
+	return cidrs
+}
+
+func MatchVpc(vpc types.Vpc, matcher Matcher) bool {
+	// an IP address finds the VPCs it belongs to, a CIDR block the VPCs overlapping it
+	if matched, ok := matcher.MatchNetwork(nil, VpcCidrBlocks(vpc)); ok {
+		return matched
+	}
 
-	return filteredVpcs, nil
+	return matcher.Match(aws.ToString(vpc.CidrBlock), aws.ToString(vpc.VpcId)) ||
+		matcher.MatchTags(Ec2Tags(vpc.Tags))
 }
@@ -1,17 +1,178 @@
 package web
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/aviadhaham/cloudcate/internal/config"
+	"github.com/aviadhaham/cloudcate/internal/index"
 	"github.com/aviadhaham/cloudcate/internal/search"
+	"github.com/aviadhaham/cloudcate/internal/services"
 
 	"github.com/gin-gonic/contrib/static"
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(profiles []string) *gin.Engine {
+// searchRequest holds the query parameters shared by the search endpoints.
+type searchRequest struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	query  search.Query
+}
+
+// newSearchRequest parses the search query parameters, it responds with 400 and returns false when they are invalid.
+// The caller must call cancel once the search is over.
+// Searches are answered from the index when there is one, unless source=live.
+func newSearchRequest(c *gin.Context, idx *index.Index) (*searchRequest, bool) {
+	badRequest := func(err error) (*searchRequest, bool) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+
+	match := services.MatchOptions{Mode: c.Query("match")}
+	if c.Query("case_sensitive") != "" {
+		caseSensitive, err := strconv.ParseBool(c.Query("case_sensitive"))
+		if err != nil {
+			return badRequest(fmt.Errorf("invalid case_sensitive %q: expected true or false", c.Query("case_sensitive")))
+		}
+		match.CaseSensitive = caseSensitive
+	}
+
+	query, err := search.NewQuery(c.Query("resource_type"), c.Query("resource_subtype"), c.Query("resource_name"), match)
+	if err != nil {
+		return badRequest(err)
+	}
+	req := &searchRequest{query: query}
+
+	if c.Query("max_pages") != "" {
+		maxPages, err := strconv.Atoi(c.Query("max_pages"))
+		if err != nil || maxPages < 0 {
+			return badRequest(fmt.Errorf("invalid max_pages %q: expected a number of pages, 0 for no limit", c.Query("max_pages")))
+		}
+		req.query.MaxPages = maxPages
+	}
+
+	if c.Query("dedupe_accounts") != "" {
+		dedupe, err := strconv.ParseBool(c.Query("dedupe_accounts"))
+		if err != nil {
+			return badRequest(fmt.Errorf("invalid dedupe_accounts %q: expected true or false", c.Query("dedupe_accounts")))
+		}
+		req.query.DedupeAccounts = dedupe
+	}
+
+	if c.Query("refresh") != "" {
+		refresh, err := strconv.ParseBool(c.Query("refresh"))
+		if err != nil {
+			return badRequest(fmt.Errorf("invalid refresh %q: expected true or false", c.Query("refresh")))
+		}
+		req.query.Refresh = refresh
+	}
+
+	if c.Query("raw") != "" {
+		raw, err := strconv.ParseBool(c.Query("raw"))
+		if err != nil {
+			return badRequest(fmt.Errorf("invalid raw %q: expected true or false", c.Query("raw")))
+		}
+		req.query.Raw = raw
+	}
+
+	switch c.Query("source") {
+	case "", "index":
+		if c.Query("source") == "index" && idx == nil {
+			return badRequest(fmt.Errorf("source=index requires the crawler, set INDEX_PATH to enable it"))
+		}
+		req.query.Index = idx
+	case "live":
+	default:
+		return badRequest(fmt.Errorf("invalid source %q: expected index or live", c.Query("source")))
+	}
+
+	if c.Query("timeout") != "" {
+		timeout, err := parseTimeout(c.Query("timeout"))
+		if err != nil {
+			return badRequest(err)
+		}
+		req.ctx, req.cancel = context.WithTimeout(c.Request.Context(), timeout)
+	} else {
+		req.ctx, req.cancel = context.WithCancel(c.Request.Context())
+	}
+
+	return req, true
+}
+
+// accessKeyIdPattern is the format of an access key ID, as accepted by sts:GetAccessKeyInfo.
+var accessKeyIdPattern = regexp.MustCompile(`^\w{16,128}$`)
+
+// lookupRequest holds the query parameters shared by the endpoints looking a single resource up.
+type lookupRequest struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	raw    bool
+}
+
+// newLookupRequest parses the raw and timeout query parameters, it responds with 400 and returns false when they
+// are invalid. The caller must call cancel once the lookup is over.
+func newLookupRequest(c *gin.Context) (*lookupRequest, bool) {
+	req := &lookupRequest{}
+	if c.Query("raw") != "" {
+		raw, err := strconv.ParseBool(c.Query("raw"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("invalid raw %q: expected true or false", c.Query("raw")),
+			})
+			return nil, false
+		}
+		req.raw = raw
+	}
+
+	if c.Query("timeout") != "" {
+		timeout, err := parseTimeout(c.Query("timeout"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		req.ctx, req.cancel = context.WithTimeout(c.Request.Context(), timeout)
+	} else {
+		req.ctx, req.cancel = context.WithCancel(c.Request.Context())
+	}
+	return req, true
+}
+
+// respondLookup responds with the result of a single resource lookup: 404 with the error and the notFound details
+// when no profile has access to the account or the resource doesn't exist, and 502 when the profiles of the
+// account failed to look it up.
+func respondLookup(c *gin.Context, response interface{}, found bool, err error, notFound gin.H) {
+	var noProfileErr *search.NoProfileError
+	switch {
+	case errors.As(err, &noProfileErr), errors.Is(err, services.ErrNotFound):
+		notFound["error"] = err.Error()
+		c.JSON(http.StatusNotFound, notFound)
+	case err != nil:
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+		})
+	case !found:
+		// every profile of the account failed, the errors of the response tell why
+		c.JSON(http.StatusBadGateway, response)
+	default:
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// NewRouter builds the API router, idx is nil when the crawler is disabled.
+func NewRouter(profiles []search.Profile, idx *index.Index) *gin.Engine {
 	r := gin.Default()
 
 	// Serve react app
@@ -19,21 +180,376 @@ func NewRouter(profiles []string) *gin.Engine {
 
 	api := r.Group("/api")
 	{
-		api.GET("/search", func(c *gin.Context) {
-			resourceName := c.Query("resource_name")
-			resourceType := c.Query("resource_type")
-			resourceSubType := c.Query("resource_subtype")
+		api.GET("/profiles", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"profiles": profiles,
+			})
+		})
+
+		api.GET("/resource-types", func(c *gin.Context) {
+			resourceTypes := []gin.H{}
+			for _, searcher := range search.Searchers() {
+				resourceTypes = append(resourceTypes, gin.H{
+					"resource_type":     searcher.Type(),
+					"resource_subtypes": searcher.SubTypes(),
+					"global":            searcher.Global(),
+				})
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"resource_types": resourceTypes,
+			})
+		})
 
-			results, err := search.FindResources(profiles, config.ServicesGlobality, resourceType, resourceSubType, resourceName)
+		api.GET("/schema/resource", func(c *gin.Context) {
+			c.Header("Content-Type", "application/schema+json")
+			c.JSON(http.StatusOK, search.ResourceSchema())
+		})
+
+		api.GET("/crawl/status", func(c *gin.Context) {
+			if !requireIndex(c, idx) {
+				return
+			}
+
+			statuses, err := idx.Statuses()
 			if err != nil {
-				log.Fatalf("Failed to search resources: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"statuses": statuses,
+			})
+		})
+
+		api.GET("/changes", func(c *gin.Context) {
+			if !requireIndex(c, idx) {
+				return
 			}
 
+			if c.Query("since") == "" {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "since is required",
+				})
+				return
+			}
+			since, err := parseTime(c.Query("since"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			until := time.Now()
+			if c.Query("until") != "" {
+				until, err = parseTime(c.Query("until"))
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{
+						"error": err.Error(),
+					})
+					return
+				}
+			}
+
+			changes, found, err := findChanges(idx, since, until, index.ChangeFilter{
+				Profile:      c.Query("profile"),
+				Region:       c.Query("region"),
+				ResourceType: c.Query("resource_type"),
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			if !found {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "there is no crawl snapshot yet",
+				})
+				return
+			}
+			c.JSON(http.StatusOK, changes)
+		})
+
+		api.GET("/saved-searches", func(c *gin.Context) {
+			if !requireIndex(c, idx) {
+				return
+			}
+
+			savedSearches, err := idx.SavedSearches()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
 			c.JSON(http.StatusOK, gin.H{
-				"results": results,
+				"saved_searches": savedSearches,
+			})
+		})
+
+		api.POST("/saved-searches", func(c *gin.Context) {
+			if !requireIndex(c, idx) {
+				return
+			}
+
+			var savedSearch index.SavedSearch
+			if err := c.ShouldBindJSON(&savedSearch); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": fmt.Sprintf("invalid saved search: %v", err),
+				})
+				return
+			}
+			if err := validateSavedSearch(&savedSearch); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+
+			if err := idx.PutSavedSearch(savedSearch); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusCreated, savedSearch)
+		})
+
+		api.DELETE("/saved-searches/:id", func(c *gin.Context) {
+			if !requireIndex(c, idx) {
+				return
+			}
+
+			found, err := idx.DeleteSavedSearch(c.Param("id"))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			if !found {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": fmt.Sprintf("saved search %s was not found", c.Param("id")),
+				})
+				return
+			}
+			c.Status(http.StatusNoContent)
+		})
+
+		api.GET("/arn", func(c *gin.Context) {
+			resourceArn, err := services.ParseResourceArn(c.Query("arn"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			req, ok := newLookupRequest(c)
+			if !ok {
+				return
+			}
+			defer req.cancel()
+
+			response, err := search.DescribeArn(req.ctx, profiles, resourceArn, req.raw)
+			respondLookup(c, response, response.Resource != nil, err, gin.H{"arn": resourceArn})
+		})
+
+		api.GET("/access-key", func(c *gin.Context) {
+			accessKeyId := c.Query("access_key_id")
+			if !accessKeyIdPattern.MatchString(accessKeyId) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": fmt.Sprintf("invalid access_key_id %q: expected an access key ID such as AKIA...", accessKeyId),
+				})
+				return
+			}
+			req, ok := newLookupRequest(c)
+			if !ok {
+				return
+			}
+			defer req.cancel()
+
+			response, err := search.FindAccessKey(req.ctx, profiles, accessKeyId, req.raw)
+			respondLookup(c, response, response.Resource != nil, err, gin.H{
+				"access_key_id": accessKeyId,
+				"account_id":    response.AccountId,
+				"errors":        response.Errors,
+			})
+		})
+
+		api.GET("/search", func(c *gin.Context) {
+			req, ok := newSearchRequest(c, idx)
+			if !ok {
+				return
+			}
+			defer req.cancel()
+
+			c.JSON(http.StatusOK, search.FindResources(req.ctx, profiles, req.query))
+		})
+
+		api.POST("/search/bulk", func(c *gin.Context) {
+			var body struct {
+				Terms []string `json:"terms"`
+			}
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": fmt.Sprintf("invalid body, expected {\"terms\": [...]}: %v", err),
+				})
+				return
+			}
+			terms := search.UniqueTerms(body.Terms)
+			if len(terms) == 0 || len(terms) > maxBulkTerms {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": fmt.Sprintf("expected between 1 and %d terms", maxBulkTerms),
+				})
+				return
+			}
+
+			req, ok := newSearchRequest(c, idx)
+			if !ok {
+				return
+			}
+			defer req.cancel()
+
+			for _, term := range terms {
+				if _, err := search.ParseTerm(term, req.query.Match); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{
+						"error": fmt.Sprintf("invalid term %q: %v", term, err),
+					})
+					return
+				}
+			}
+			req.query.Terms = terms
+
+			c.JSON(http.StatusOK, search.FindResourcesBulk(req.ctx, profiles, req.query))
+		})
+
+		api.GET("/search/stream", func(c *gin.Context) {
+			req, ok := newSearchRequest(c, idx)
+			if !ok {
+				return
+			}
+			defer req.cancel()
+
+			ndjson := c.Query("format") == "ndjson" || strings.Contains(c.GetHeader("Accept"), "application/x-ndjson")
+			if ndjson {
+				c.Header("Content-Type", "application/x-ndjson")
+			} else {
+				c.Header("Content-Type", "text/event-stream")
+			}
+			c.Header("Cache-Control", "no-cache")
+			c.Header("X-Accel-Buffering", "no")
+			c.Status(http.StatusOK)
+
+			encoder := json.NewEncoder(c.Writer)
+			search.StreamResources(req.ctx, profiles, req.query, func(event search.Event) {
+				// the client is gone, the search itself stops through the request context
+				if c.Request.Context().Err() != nil {
+					return
+				}
+				if ndjson {
+					_ = encoder.Encode(event)
+				} else {
+					c.SSEvent(event.Event, event)
+				}
+				c.Writer.Flush()
 			})
 		})
 	}
 
 	return r
 }
+
+// maxBulkTerms caps the terms of a bulk search, every listed resource is matched against each of them.
+const maxBulkTerms = 1000
+
+// requireIndex responds with 404 and returns false when the crawler, and so the index, is disabled.
+func requireIndex(c *gin.Context, idx *index.Index) bool {
+	if idx == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "the crawler is disabled, set INDEX_PATH to enable it",
+		})
+		return false
+	}
+	return true
+}
+
+// validateSavedSearch checks a new saved search and fills in its ID, creation time and default webhook format.
+func validateSavedSearch(savedSearch *index.SavedSearch) error {
+	if _, err := search.NewQuery(savedSearch.ResourceType, savedSearch.ResourceSubType, savedSearch.ResourceName, savedSearch.MatchOptions()); err != nil {
+		return err
+	}
+	if savedSearch.WebhookUrl == "" {
+		return fmt.Errorf("webhook_url is required")
+	}
+	switch savedSearch.WebhookFormat {
+	case "":
+		savedSearch.WebhookFormat = index.WebhookFormatJson
+	case index.WebhookFormatJson, index.WebhookFormatSlack:
+	default:
+		return fmt.Errorf("invalid webhook_format %q: expected json or slack", savedSearch.WebhookFormat)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	savedSearch.Id = hex.EncodeToString(id)
+	if savedSearch.Name == "" {
+		savedSearch.Name = savedSearch.Id
+	}
+	savedSearch.CreatedAt = time.Now()
+	savedSearch.LastEvaluatedAt = nil
+	savedSearch.LastError = ""
+	savedSearch.Results = nil
+	return nil
+}
+
+// parseTimeout accepts either a Go duration ("30s", "1m30s") or a plain number of seconds.
+func parseTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		value = fmt.Sprintf("%ds", seconds)
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: expected a positive duration such as 30s or a number of seconds", value)
+	}
+	return timeout, nil
+}
+
+// findChanges compares the snapshot taken at since with the one taken at until, it returns false when
+// nothing was crawled yet.
+func findChanges(idx *index.Index, since time.Time, until time.Time, filter index.ChangeFilter) (gin.H, bool, error) {
+	from, found, err := idx.SnapshotAt(since)
+	if err != nil || !found {
+		return nil, found, err
+	}
+	to, _, err := idx.SnapshotAt(until)
+	if err != nil {
+		return nil, false, err
+	}
+
+	changes, err := idx.Changes(from, to, filter)
+	if err != nil {
+		return nil, false, err
+	}
+	return gin.H{
+		"from":    from,
+		"to":      to,
+		"changes": changes,
+	}, true, nil
+}
+
+// parseTime accepts either an RFC 3339 time or a duration back from now ("24h" is yesterday).
+func parseTime(value string) (time.Time, error) {
+	if ago, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-ago), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected an RFC 3339 time or a duration such as 24h", value)
+	}
+	return t, nil
+}
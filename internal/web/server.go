@@ -2,23 +2,29 @@ package web
 
 import (
 	"log"
+
+	"github.com/aviadhaham/cloudcate/internal/index"
+	"github.com/aviadhaham/cloudcate/internal/search"
 )
 
 type server struct {
 	port     string
-	profiles []string
+	profiles []search.Profile
 	regions  []string
+	index    *index.Index
 }
 
-func NewServer(port string, profiles []string) *server {
+// NewServer creates the API server, idx is the crawler's index or nil when the crawler is disabled.
+func NewServer(port string, profiles []search.Profile, idx *index.Index) *server {
 	return &server{
 		port:     port,
 		profiles: profiles,
+		index:    idx,
 	}
 }
 
 func (s *server) Run() {
-	r := NewRouter(s.profiles)
+	r := NewRouter(s.profiles, s.index)
 
 	err := r.Run(":" + s.port)
 	if err != nil {
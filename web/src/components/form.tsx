@@ -2,7 +2,7 @@ import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { Select, SelectContent, SelectGroup, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select";
 import { Separator } from "@/components/ui/separator";
-import { AllSearchResults } from "@/types/search-results";
+import { Resource } from "@/types/search-results";
 import { LoaderCircle } from "lucide-react";
 import { useState } from "react";
 
@@ -13,7 +13,7 @@ type Props = {
   setTypeQuery: (value: string) => void;
   subTypeQuery: string;
   setSubTypeQuery: (value: string) => void;
-  onResults: (data: AllSearchResults[]) => void;
+  onResults: (data: Resource[] | null) => void;
 };
 
 function isSearchQueryValid(query: string) {
@@ -28,6 +28,7 @@ function isSearchQueryValid(query: string) {
 
 export default function Form(props: Props) {
   const [isLoading, setIsLoading] = useState(false);
+  const [progress, setProgress] = useState<{ done: number; total: number } | null>(null);
 
   const handleTypeValueChange = (value: string) => {
     const types = value.split(":");
@@ -37,39 +38,51 @@ export default function Form(props: Props) {
     props.setSubTypeQuery(subType);
   };
 
-  const sendSearchRequest = async () => {
+  const sendSearchRequest = () => {
     // Clear the results when a new search is made
     props.onResults([]);
+    setProgress(null);
     setIsLoading(true);
 
-    let url = `/api/search?resource_name=${props.searchQuery}&resource_type=${props.typeQuery}`;
+    let url = `/api/search/stream?resource_name=${encodeURIComponent(props.searchQuery)}&resource_type=${props.typeQuery}`;
 
     if (props.subTypeQuery !== "") {
       url += `&resource_subtype=${props.subTypeQuery}`;
     }
 
-    const response = await fetch(url);
+    // Results are streamed region by region, so they are shown as soon as they arrive
+    let results: Resource[] = [];
+    const source = new EventSource(url);
 
-    if (!response.ok) {
-      console.error(`Error fetching data: ${response.status} ${response.statusText}`);
-      setIsLoading(false);
-      return;
-    }
+    source.addEventListener("results", (e) => {
+      const data = JSON.parse((e as MessageEvent).data);
+      results = [...results, ...data.results];
+      props.onResults(results);
+    });
+
+    source.addEventListener("progress", (e) => {
+      const data = JSON.parse((e as MessageEvent).data);
+      setProgress({ done: data.done, total: data.total });
+    });
 
-    const contentType = response.headers.get("content-type");
-    if (contentType && contentType.indexOf("application/json") !== -1) {
-      try {
-        setIsLoading(true);
-        const data = await response.json();
-        props.onResults(data.results);
-      } catch (e) {
-        console.error(e);
-      } finally {
-        setIsLoading(false);
+    source.addEventListener("search_error", (e) => {
+      const data = JSON.parse((e as MessageEvent).data);
+      console.warn(`Search error (${data.error.class}):`, data.error);
+    });
+
+    source.addEventListener("summary", () => {
+      source.close();
+      setIsLoading(false);
+      if (results.length === 0) {
+        props.onResults(null);
       }
-    } else {
-      console.error("The response is not a valid JSON");
-    }
+    });
+
+    source.onerror = () => {
+      console.error("Error streaming search results");
+      source.close();
+      setIsLoading(false);
+    };
   };
 
   return (
@@ -77,7 +90,7 @@ export default function Form(props: Props) {
       <div className="flex items-center justify-center my-10 gap-x-6">
         <div className="w-[300px]">
           <label htmlFor="resource-name" className="block text-sm font-medium leading-6 text-gray-900">
-            Resource Name (may be partial)
+            Search (text, or a query like state:running)
           </label>
           <div>
             <Input
@@ -103,7 +116,9 @@ export default function Form(props: Props) {
               </SelectTrigger>
               <SelectContent>
                 <SelectGroup>
-                  <SelectItem value="vpc">VPC (by ID, CIDR, or Tags)</SelectItem>
+                  <SelectItem value="all">All Resource Types</SelectItem>
+                  <SelectItem value="vpc">VPC (by ID, CIDR, IP, or Tags)</SelectItem>
+                  <SelectItem value="subnet">Subnet (by ID, CIDR, IP, or Tags)</SelectItem>
                   <SelectItem value="s3">S3 Bucket</SelectItem>
                   <SelectItem value="dns">DNS (Hosted Zone or Record)</SelectItem>
                   <SelectItem value="loadbalancer">Load Balancer</SelectItem>
@@ -111,7 +126,9 @@ export default function Form(props: Props) {
                   <SelectItem value="iam:key">IAM (Access Key)</SelectItem>
                   <SelectItem value="iam:user">IAM (User)</SelectItem>
                   <SelectItem value="elastic_ip">Elastic IP</SelectItem>
+                  <SelectItem value="ip">IP Address Owner (any network interface)</SelectItem>
                   <SelectItem value="cloudfront">CloudFront Distribution (by ID or Domain name)</SelectItem>
+                  <SelectItem value="tags">Tagged Resources, any service (by Key=Value, Key or =Value)</SelectItem>
                 </SelectGroup>
               </SelectContent>
             </Select>
@@ -131,6 +148,11 @@ export default function Form(props: Props) {
             <LoaderCircle className="w-10 h-10" />
           </div>
         )}
+        {isLoading && progress && (
+          <span className="mt-4 text-sm text-gray-600">
+            {progress.done} of {progress.total} region scans done
+          </span>
+        )}
       </div>
     </>
   );
@@ -6,31 +6,57 @@ import {
   TableHeader,
   TableRow,
 } from "@/components/ui/table";
-import { AllSearchResults } from "@/types/search-results";
+import { Resource } from "@/types/search-results";
+
+function formatValue(value: unknown) {
+  if (Array.isArray(value)) {
+    return value.join(", ");
+  }
+  if (typeof value === "boolean") {
+    return String(value);
+  }
+  if (value !== null && typeof value === "object") {
+    return Object.entries(value)
+      .map(([key, v]) => `${key}=${v}`)
+      .join(", ");
+  }
+  return value as string;
+}
+
+// The attributes of a resource are shown as columns of their own, next to the fields every resource has
+function flatten(result: Resource): Record<string, unknown> {
+  const { attributes, ...fields } = result;
+  const row: Record<string, unknown> = { ...fields, ...attributes };
+  delete row.raw;
+  return row;
+}
 
 export default function ResultsTable({
   results,
 }: {
-  results: AllSearchResults[];
+  results: Resource[];
 }) {
   if (results.length === 0) {
     return;
   }
+  // Results of different resource types have different attributes, so the columns are the union of all of them
+  const rows = results.map(flatten);
+  const columns = [...new Set(rows.flatMap((row) => Object.keys(row)))];
   return (
     <Table className="mt-10">
       <TableHeader>
         <TableRow>
-          {Object.keys(results[0]).map((key) => (
-            <TableHead>{key}</TableHead>
+          {columns.map((key) => (
+            <TableHead key={key}>{key}</TableHead>
           ))}
         </TableRow>
       </TableHeader>
       <TableBody>
-        {results.map((result, rowIndex) => (
+        {rows.map((row, rowIndex) => (
           <TableRow key={rowIndex}>
-            {Object.keys(result).map((key, keyIndex) => (
+            {columns.map((key, keyIndex) => (
               <TableCell key={keyIndex}>
-                {result[key as keyof AllSearchResults]}
+                {formatValue(row[key])}
               </TableCell>
             ))}
           </TableRow>
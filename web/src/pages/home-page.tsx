@@ -1,7 +1,7 @@
 import Form from "@/components/form";
 import Header from "@/components/header";
 import ResultsTable from "@/components/results-table";
-import { AllSearchResults } from "@/types/search-results";
+import { Resource } from "@/types/search-results";
 import { useEffect, useState } from "react";
 import { useSearchParams } from "react-router-dom";
 
@@ -10,8 +10,8 @@ export default function HomePage() {
   const [searchQuery, setSearchQuery] = useState(searchParams.get("q") || "");
   const [typeQuery, setTypeQuery] = useState(searchParams.get("t") || "");
   const [subTypeQuery, setSubTypeQuery] = useState(searchParams.get("st") || "");
-  const [results, setResults] = useState<AllSearchResults[]>([]);
-  const handleResults = (data: AllSearchResults[]) => {
+  const [results, setResults] = useState<Resource[] | null>([]);
+  const handleResults = (data: Resource[] | null) => {
     setResults(data);
   };
 
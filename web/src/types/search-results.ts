@@ -1,46 +1,20 @@
-export type SearchResult = {
-  Account: string;
-  Profile: string;
-  Region: string;
+// Resource is the envelope of every search result, see /api/schema/resource for the attributes of each type.
+export type Resource = {
+  resource_type: string;
+  resource_subtype?: string;
+  arn?: string;
+  id: string;
+  name?: string;
+  account_id: string;
+  account_alias?: string;
+  account_name?: string;
+  ou_path?: string;
+  profile: string;
+  profiles?: string[];
+  region?: string;
+  tags?: Record<string, string>;
+  created_at?: string;
+  matched_fields?: string[];
+  attributes: Record<string, unknown>;
+  raw?: unknown;
 };
-
-export type Ec2SearchResult = SearchResult &{
-  instanceId: string;
-  InstanceName: string;
-  PrivateIpAddress: string;
-  PrivateDnsName: string;
-  PublicIpAddress: string;
-  PublicDnsName: string;
-};
-
-export type S3SearchResult = SearchResult &{
-  BucketName: string;
-};
-
-export type LoadBalancerSearchResult = SearchResult &{
-  LoadBalancerName: string;
-  LoadBalancerDnsName: string;
-};
-
-export type DNSSearchResult = SearchResult &{
-  HostedZoneName: string;
-  DnsRecordName: string;
-  DnsRecordType: string;
-};
-
-export type IamSearchResult = SearchResult &{
-  UserName: string;
-};
-
-export type ElasticIpSearchResult = SearchResult &{
-  PublicIp: string;
-  InstanceId: string;
-};
-
-export type CloudfrontSearchResult = SearchResult &{
-  DistributionArn: string;
-  DistributionId: string;
-  DomainName: string;
-};
-
-export type AllSearchResults = Ec2SearchResult | S3SearchResult | LoadBalancerSearchResult | DNSSearchResult | IamSearchResult | ElasticIpSearchResult | CloudfrontSearchResult;